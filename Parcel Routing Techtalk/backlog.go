@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BacklogError describes a single problem with the backlog of a vehicle.
+type BacklogError struct {
+	VehicleID string `json:"vehicle_id"`
+	StopID    string `json:"stop_id,omitempty"`
+	Problem   string `json:"problem"`
+}
+
+func (e BacklogError) Error() string {
+	if e.StopID == "" {
+		return fmt.Sprintf("vehicle %q: backlog: %s", e.VehicleID, e.Problem)
+	}
+	return fmt.Sprintf(
+		"vehicle %q: backlog stop %q: %s", e.VehicleID, e.StopID, e.Problem,
+	)
+}
+
+// BacklogErrors holds all problems found in the backlogs of the vehicles.
+type BacklogErrors []BacklogError
+
+func (e BacklogErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// validateBacklogs checks the backlogs of the vehicles before they are passed
+// to the router and reports every problem at once: stops that do not exist,
+// stops in more than one backlog and backlogs that cannot be loaded onto the
+// vehicle. Since deliveries are all on board at the start of a route and
+// pick-ups all at its end, each of them must fit the capacity on their own.
+func validateBacklogs(
+	vehicles []Vehicle,
+	stops map[string]int,
+	quantities []int,
+	capacities []int,
+) error {
+	var errs BacklogErrors
+	assigned := make(map[string]string)
+	for v, vehicle := range vehicles {
+		deliveries, pickups := 0, 0
+		for _, id := range vehicle.Backlog {
+			s, ok := stops[id]
+			if !ok {
+				errs = append(errs, BacklogError{
+					VehicleID: vehicle.ID,
+					StopID:    id,
+					Problem:   "unknown stop",
+				})
+				continue
+			}
+			if other, ok := assigned[id]; ok {
+				errs = append(errs, BacklogError{
+					VehicleID: vehicle.ID,
+					StopID:    id,
+					Problem: fmt.Sprintf(
+						"already in the backlog of vehicle %q", other,
+					),
+				})
+				continue
+			}
+			assigned[id] = vehicle.ID
+
+			if quantities[s] < 0 {
+				deliveries -= quantities[s]
+			} else {
+				pickups += quantities[s]
+			}
+		}
+
+		load := deliveries
+		if pickups > load {
+			load = pickups
+		}
+		if load > capacities[v] {
+			errs = append(errs, BacklogError{
+				VehicleID: vehicle.ID,
+				Problem: fmt.Sprintf(
+					"load %d exceeds capacity %d", load, capacities[v],
+				),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
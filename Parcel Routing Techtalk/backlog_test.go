@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateBacklogs(t *testing.T) {
+	stops := map[string]int{"a": 0, "b": 1, "c": 2}
+	quantities := []int{-2, -2, 3}
+	capacities := []int{4, 2}
+
+	tests := []struct {
+		name     string
+		vehicles []Vehicle
+		want     BacklogErrors
+	}{
+		{
+			name: "valid",
+			vehicles: []Vehicle{
+				{ID: "v1", Backlog: []string{"a", "b", "c"}},
+				{ID: "v2"},
+			},
+		},
+		{
+			name: "unknown stop",
+			vehicles: []Vehicle{
+				{ID: "v1", Backlog: []string{"a", "x"}},
+				{ID: "v2"},
+			},
+			want: BacklogErrors{
+				{VehicleID: "v1", StopID: "x", Problem: "unknown stop"},
+			},
+		},
+		{
+			name: "duplicate assignment",
+			vehicles: []Vehicle{
+				{ID: "v1", Backlog: []string{"a"}},
+				{ID: "v2", Backlog: []string{"a"}},
+			},
+			want: BacklogErrors{
+				{
+					VehicleID: "v2",
+					StopID:    "a",
+					Problem:   `already in the backlog of vehicle "v1"`,
+				},
+			},
+		},
+		{
+			name: "every problem is reported",
+			vehicles: []Vehicle{
+				{ID: "v1", Backlog: []string{"x"}},
+				{ID: "v2", Backlog: []string{"a", "c", "y"}},
+			},
+			want: BacklogErrors{
+				{VehicleID: "v1", StopID: "x", Problem: "unknown stop"},
+				{VehicleID: "v2", StopID: "y", Problem: "unknown stop"},
+				{VehicleID: "v2", Problem: "load 3 exceeds capacity 2"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateBacklogs(test.vehicles, stops, quantities, capacities)
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var got BacklogErrors
+			if !errors.As(err, &got) {
+				t.Fatalf("expected BacklogErrors, got %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"github.com/nextmv-io/sdk/route"
+)
+
+// Break is the rule for the break a driver must take during a shift. The
+// break must start within Window, if given, and a driver may not drive more
+// than MaxDriving seconds before or after it, if given. A break is only
+// required if the route would otherwise exceed MaxDriving or still be in
+// progress when the window closes.
+type Break struct {
+	Duration   int          `json:"duration"`
+	Window     *BreakWindow `json:"window,omitempty"`
+	MaxDriving int          `json:"max_driving,omitempty"`
+}
+
+// BreakWindow is the time window a break must start in, given in seconds
+// after the start of the shift, so that one rule applies to every day.
+type BreakWindow struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// breakRule is a Break resolved for a single shift in unix time.
+type breakRule struct {
+	duration    int
+	hasWindow   bool
+	windowStart int
+	windowEnd   int
+	maxDriving  int
+	shiftEnd    int
+}
+
+// newBreakRule resolves the break for the given shift.
+func newBreakRule(b Break, shift route.TimeWindow) *breakRule {
+	rule := breakRule{
+		duration:   b.Duration,
+		maxDriving: b.MaxDriving,
+		shiftEnd:   int(shift.End.Unix()),
+	}
+	if b.Window != nil {
+		rule.hasWindow = true
+		rule.windowStart = int(shift.Start.Unix()) + b.Window.Start
+		rule.windowEnd = int(shift.Start.Unix()) + b.Window.End
+	}
+	return &rule
+}
+
+// schedule holds the times of a route with its break. The break is taken
+// after departing from the location at position, which is -1 if no break is
+// needed.
+type schedule struct {
+	position   int
+	start      int
+	arrivals   []int
+	services   []int
+	departures []int
+}
+
+// schedule places the break on a route with the given times and returns the
+// resulting times of the route. The position that delays the end of the route
+// the least is chosen, preferring earlier positions on ties. Taking the break
+// delays all later locations, unless the delay is absorbed by waiting for a
+// window to open. The windowEnds hold the latest service start for each
+// position of the route, 0 if there is none. False is returned if the break
+// cannot be placed without violating a window or the shift.
+func (b breakRule) schedule(times route.Times, windowEnds []int) (schedule, bool) {
+	arrivals := times.EstimatedArrival
+	services := times.EstimatedServiceStart
+	departures := times.EstimatedDeparture
+	last := len(arrivals) - 1
+
+	totalDriving := 0
+	for j := 1; j <= last; j++ {
+		totalDriving += arrivals[j] - departures[j-1]
+	}
+
+	// No break is needed if the driving limit is kept and the route is
+	// finished before the window closes.
+	tooLong := b.maxDriving > 0 && totalDriving > b.maxDriving
+	pastWindow := b.hasWindow && arrivals[last] > b.windowEnd
+	if !tooLong && !pastWindow {
+		return schedule{
+			position:   -1,
+			arrivals:   arrivals,
+			services:   services,
+			departures: departures,
+		}, true
+	}
+
+	var best schedule
+	found := false
+	driving := 0
+	for k := 0; k < last; k++ {
+		if k > 0 {
+			driving += arrivals[k] - departures[k-1]
+		}
+		// Later positions only increase the driving before the break.
+		if b.maxDriving > 0 && driving > b.maxDriving {
+			break
+		}
+		if b.maxDriving > 0 && totalDriving-driving > b.maxDriving {
+			continue
+		}
+
+		start := departures[k]
+		if b.hasWindow && start < b.windowStart {
+			start = b.windowStart
+		}
+		// Later positions only start the break later.
+		if b.hasWindow && start > b.windowEnd {
+			break
+		}
+
+		s, ok := b.delay(k, start, arrivals, services, departures, windowEnds)
+		if ok && (!found || s.arrivals[last] < best.arrivals[last]) {
+			best, found = s, true
+		}
+	}
+
+	return best, found
+}
+
+// delay returns the times of the route with the break taken after position k,
+// starting at the given time, and whether all windows and the shift are kept.
+func (b breakRule) delay(
+	k int,
+	start int,
+	arrivals []int,
+	services []int,
+	departures []int,
+	windowEnds []int,
+) (schedule, bool) {
+	s := schedule{
+		position:   k,
+		start:      start,
+		arrivals:   append([]int{}, arrivals...),
+		services:   append([]int{}, services...),
+		departures: append([]int{}, departures...),
+	}
+
+	delay := start + b.duration - departures[k]
+	for j := k + 1; j < len(arrivals) && delay > 0; j++ {
+		s.arrivals[j] += delay
+		if s.arrivals[j] > s.services[j] {
+			delay = s.arrivals[j] - services[j]
+			s.services[j] = s.arrivals[j]
+		} else {
+			delay = 0
+		}
+		s.departures[j] += delay
+		if windowEnds[j] != 0 && s.services[j] > windowEnds[j] {
+			return schedule{}, false
+		}
+	}
+
+	return s, s.arrivals[len(arrivals)-1] <= b.shiftEnd
+}
+
+// BreakConstraint implements the route.VehicleConstraint interface. It
+// ensures that the break of every driver can be scheduled on its route.
+type BreakConstraint struct {
+	vehicles map[string]int
+	// rules holds the break rule of each vehicle, nil if it has none.
+	rules []*breakRule
+	// windowEnds holds the latest service start of each stop, 0 if none.
+	windowEnds []int
+}
+
+// Violated reports whether the break cannot be placed on the route.
+func (c BreakConstraint) Violated(
+	vehicle route.PartialVehicle,
+) (route.VehicleConstraint, bool) {
+	rule := c.rules[c.vehicles[vehicle.ID()]]
+	r := vehicle.Route()
+	if rule == nil || len(r) <= 2 {
+		return c, false
+	}
+
+	_, ok := rule.schedule(vehicle.Times(), routeWindowEnds(r, c.windowEnds))
+	return c, !ok
+}
+
+// windowEnds returns the latest service start of each stop, 0 if it has no
+// window.
+func windowEnds(windows []route.Window) []int {
+	ends := make([]int, len(windows))
+	for s, window := range windows {
+		if window != (route.Window{}) {
+			ends[s] = int(window.TimeWindow.End.Unix())
+		}
+	}
+	return ends
+}
+
+// routeWindowEnds returns the window ends of the locations of a route. The
+// start and end locations of vehicles have no window.
+func routeWindowEnds(locations []int, windowEnds []int) []int {
+	ends := make([]int, len(locations))
+	for i, location := range locations {
+		if location < len(windowEnds) {
+			ends[i] = windowEnds[location]
+		}
+	}
+	return ends
+}
+
+// vehicleConstraints combines several vehicle constraints into one, since the
+// router holds a single constraint per vehicle.
+type vehicleConstraints []route.VehicleConstraint
+
+// Violated reports whether any of the constraints is violated.
+func (c vehicleConstraints) Violated(
+	vehicle route.PartialVehicle,
+) (route.VehicleConstraint, bool) {
+	next := make(vehicleConstraints, len(c))
+	for i, constraint := range c {
+		updated, violated := constraint.Violated(vehicle)
+		if violated {
+			return c, true
+		}
+		next[i] = updated
+	}
+	return next, false
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+func TestBreakRuleSchedule(t *testing.T) {
+	// A route from the depot over two stops back to the depot. Every leg
+	// takes 100 seconds of driving and every stop 10 seconds of service.
+	times := route.Times{
+		EstimatedArrival:      []int{0, 100, 210, 320},
+		EstimatedServiceStart: []int{0, 100, 210, 320},
+		EstimatedDeparture:    []int{0, 110, 220, 320},
+	}
+
+	tests := []struct {
+		name       string
+		rule       breakRule
+		windowEnds []int
+		want       schedule
+		ok         bool
+	}{
+		{
+			name: "not needed",
+			rule: breakRule{duration: 30, maxDriving: 300, shiftEnd: 1000},
+			want: schedule{
+				position:   -1,
+				arrivals:   times.EstimatedArrival,
+				services:   times.EstimatedServiceStart,
+				departures: times.EstimatedDeparture,
+			},
+			ok: true,
+		},
+		{
+			name: "after max driving",
+			rule: breakRule{duration: 30, maxDriving: 200, shiftEnd: 1000},
+			want: schedule{
+				position:   1,
+				start:      110,
+				arrivals:   []int{0, 100, 240, 350},
+				services:   []int{0, 100, 240, 350},
+				departures: []int{0, 110, 250, 350},
+			},
+			ok: true,
+		},
+		{
+			name: "waits for window",
+			rule: breakRule{
+				duration:    30,
+				hasWindow:   true,
+				windowStart: 200,
+				windowEnd:   250,
+				shiftEnd:    1000,
+			},
+			want: schedule{
+				position:   2,
+				start:      220,
+				arrivals:   []int{0, 100, 210, 350},
+				services:   []int{0, 100, 210, 350},
+				departures: []int{0, 110, 220, 350},
+			},
+			ok: true,
+		},
+		{
+			name: "stop window violated",
+			rule: breakRule{
+				duration:    30,
+				hasWindow:   true,
+				windowStart: 100,
+				windowEnd:   150,
+				shiftEnd:    1000,
+			},
+			windowEnds: []int{0, 0, 220, 0},
+			ok:         false,
+		},
+		{
+			name: "shift end violated",
+			rule: breakRule{duration: 30, maxDriving: 200, shiftEnd: 340},
+			ok:   false,
+		},
+		{
+			name: "driving limit cannot be kept",
+			rule: breakRule{duration: 30, maxDriving: 100, shiftEnd: 1000},
+			ok:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			windowEnds := test.windowEnds
+			if windowEnds == nil {
+				windowEnds = make([]int, 4)
+			}
+			got, ok := test.rule.schedule(times, windowEnds)
+			if ok != test.ok {
+				t.Fatalf("ok = %v, want %v", ok, test.ok)
+			}
+			if ok && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVehicleDataUpdateBreak(t *testing.T) {
+	// Stops 0 and 1 are followed by the start (2) and end (3) location of the
+	// vehicle, with the times of TestBreakRuleSchedule. Stop 1 should be
+	// served by 210, which the break after stop 0 delays to 240.
+	start := time.Unix(0, 0)
+	v := vehicleData{
+		vehicles:            map[string]int{"vehicle": 0},
+		measures:            []route.ByIndex{route.Constant(0)},
+		initializationCosts: []float64{0},
+		softWindows: []*SoftWindow{
+			nil,
+			{Start: start, End: start.Add(210 * time.Second), LatenessPenalty: 2},
+		},
+		days:       []int{0},
+		breakRules: []*breakRule{nil},
+		windowEnds: []int{0, 0},
+	}
+	vehicle := partialVehicle{
+		route: []int{2, 0, 1, 3},
+		times: route.Times{
+			EstimatedArrival:      []int{0, 100, 210, 320},
+			EstimatedServiceStart: []int{0, 100, 210, 320},
+			EstimatedDeparture:    []int{0, 110, 220, 320},
+		},
+	}
+
+	if _, value, _ := v.Update(vehicle); value != 0 {
+		t.Errorf("without break: value = %d, want 0", value)
+	}
+
+	v.breakRules = []*breakRule{
+		{duration: 30, maxDriving: 200, shiftEnd: 1000},
+	}
+	if _, value, _ := v.Update(vehicle); value != 60 {
+		t.Errorf("with break: value = %d, want 60", value)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import "fmt"
+
+// Compatibility describes which package types may travel on the same route.
+// Packages of the same type are always compatible unless that pair is
+// forbidden explicitly. Packages of different types are compatible if their
+// pair is allowed or, with AllowUnlisted set, if it is not forbidden. Pairs
+// are unordered.
+//
+// By default stops without a type are wildcards that are compatible with
+// everything. If UntypedWildcard is false, they are treated as packages of the
+// empty type "" instead, which can be used in the pairs like any other type.
+type Compatibility struct {
+	Allowed         [][2]string `json:"allowed"`
+	Forbidden       [][2]string `json:"forbidden"`
+	AllowUnlisted   bool        `json:"allow_unlisted"`
+	UntypedWildcard *bool       `json:"untyped_wildcard,omitempty"`
+}
+
+// untypedWildcard returns whether stops without a type are wildcards.
+func (c Compatibility) untypedWildcard() bool {
+	return c.UntypedWildcard == nil || *c.UntypedWildcard
+}
+
+// packageTypes maps the package type of each stop to an index and returns
+// these indices together with a matrix that holds for each pair of indices
+// whether the types are compatible. Wildcard stops get the index -1. A nil
+// compatibility only allows packages of the same type on a route.
+func packageTypes(
+	stopTypes []string,
+	compatibility *Compatibility,
+) ([]int, [][]bool, error) {
+	if compatibility == nil {
+		compatibility = &Compatibility{}
+	}
+
+	index := map[string]int{}
+	indices := make([]int, len(stopTypes))
+	for s, stopType := range stopTypes {
+		if stopType == "" && compatibility.untypedWildcard() {
+			indices[s] = -1
+			continue
+		}
+		if _, ok := index[stopType]; !ok {
+			index[stopType] = len(index)
+		}
+		indices[s] = index[stopType]
+	}
+
+	compatible := make([][]bool, len(index))
+	for t := range compatible {
+		compatible[t] = make([]bool, len(index))
+		for u := range compatible[t] {
+			compatible[t][u] = t == u || compatibility.AllowUnlisted
+		}
+	}
+
+	allowed := map[[2]string]bool{}
+	for _, pair := range compatibility.Allowed {
+		allowed[pair] = true
+		allowed[[2]string{pair[1], pair[0]}] = true
+		setCompatible(compatible, index, pair, true)
+	}
+	for _, pair := range compatibility.Forbidden {
+		if allowed[pair] {
+			return nil, nil, fmt.Errorf(
+				"package types %q and %q are both allowed and forbidden",
+				pair[0], pair[1],
+			)
+		}
+		setCompatible(compatible, index, pair, false)
+	}
+
+	return indices, compatible, nil
+}
+
+// setCompatible sets the compatibility of both orders of a pair of types.
+// Types that no stop uses are irrelevant and therefore skipped.
+func setCompatible(
+	compatible [][]bool,
+	index map[string]int,
+	pair [2]string,
+	value bool,
+) {
+	t, ok := index[pair[0]]
+	if !ok {
+		return
+	}
+	u, ok := index[pair[1]]
+	if !ok {
+		return
+	}
+	compatible[t][u] = value
+	compatible[u][t] = value
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/nextmv-io/sdk"
-	"github.com/nextmv-io/sdk/route"
 	"github.com/nextmv-io/sdk/run"
 	"github.com/nextmv-io/sdk/run/encode"
 )
@@ -84,12 +83,17 @@ type meta[Options, Solution any] struct {
 }
 
 type custom struct {
-	Routing        routing `json:"routing"`
-	UsedVehicles   int     `json:"used_vehicles"`
-	Lateness       int     `json:"lateness"`
-	Earliness      int     `json:"earliness"`
-	TotalDuration  int     `json:"total_duration"`
-	LifoViolations int     `json:"lifo_violations"`
+	Routing            routing        `json:"routing"`
+	UsedVehicles       int            `json:"used_vehicles"`
+	UnassignedByType   map[string]int `json:"unassigned_by_type"`
+	InitializationCost float64        `json:"initialization_cost"`
+	BacklogKept        int            `json:"backlog_kept"`
+	DriveTime          int            `json:"drive_time"`
+	WaitTime           int            `json:"wait_time"`
+	Lateness           int            `json:"lateness"`
+	Earliness          int            `json:"earliness"`
+	SoftWindowPenalty  int            `json:"soft_window_penalty"`
+	DeferralPenalty    int            `json:"deferral_penalty"`
 }
 
 type routing struct {
@@ -143,7 +147,16 @@ func (g *genericEncoder[Solution, Options]) Encode( //nolint:gocyclo
 
 	if outputPather, ok := runnerCfg.(run.OutputPather); ok {
 		if strings.HasSuffix(outputPather.OutputPath(), ".gz") {
-			ioWriter = gzip.NewWriter(ioWriter)
+			gzipWriter := gzip.NewWriter(ioWriter)
+			ioWriter = gzipWriter
+			// The gzip writer is closed before the writer, which writes the
+			// trailer of the compressed data.
+			defer func() {
+				tempErr := gzipWriter.Close()
+				if err == nil {
+					err = tempErr
+				}
+			}()
 		}
 	}
 
@@ -175,9 +188,11 @@ func (g *genericEncoder[Solution, Options]) Encode( //nolint:gocyclo
 		for solution := range solutions {
 			m.Solutions = append(m.Solutions, solution)
 		}
+		// The statistics describe the last solution, which is the best one
+		// found.
 		if len(m.Solutions) > 0 {
 			s := output{}
-			b, err := json.Marshal(m.Solutions[0])
+			b, err := json.Marshal(m.Solutions[len(m.Solutions)-1])
 			if err != nil {
 				return err
 			}
@@ -186,38 +201,34 @@ func (g *genericEncoder[Solution, Options]) Encode( //nolint:gocyclo
 				return err
 			}
 
-			assigned := 0
-			usedVehicles := 0
-			for _, v := range s.Store.Vehicles {
-				if len(v.Route) > 2 {
-					assigned += len(v.Route) - 2
-					usedVehicles++
+			// Only plans in the custom format of this model have a summary.
+			if summary := s.Store.Summary; summary != nil {
+				var value float64
+				if s.Statistics.Value != nil {
+					value = float64(*s.Statistics.Value)
 				}
-			}
-
-			unassigned := 0
-			if len(s.Store.Unassigned) > 0 {
-				unassigned = len(s.Store.Unassigned)
-			}
-
-			if s.Store.Vehicles != nil {
 				m.Statistics = statisticsOut{
 					Schema: "v1",
 					Result: result{
-						Value:   float64(*s.Statistics.Value),
+						Value:   value,
 						Elapsed: s.Statistics.Time.Elapsed.Seconds(),
 						Custom: custom{
 							Routing: routing{
 								Stops: stops{
-									Unassigned: unassigned,
-									Assigned:   assigned,
+									Unassigned: summary.Unassigned,
+									Assigned:   summary.Assigned,
 								},
 							},
-							UsedVehicles:   usedVehicles,
-							Lateness:       s.Store.Lateness,
-							Earliness:      s.Store.Earliness,
-							TotalDuration:  s.Store.TotalDuration,
-							LifoViolations: s.Store.NumLifoViolations,
+							UsedVehicles:       summary.VehiclesUsed,
+							UnassignedByType:   summary.UnassignedByType,
+							InitializationCost: summary.InitializationCost,
+							BacklogKept:        summary.BacklogKept,
+							DriveTime:          summary.DriveTime,
+							WaitTime:           summary.WaitTime,
+							Lateness:           summary.Lateness,
+							Earliness:          summary.Earliness,
+							SoftWindowPenalty:  summary.SoftWindowPenalty,
+							DeferralPenalty:    summary.DeferralPenalty,
 						},
 					},
 				}
@@ -249,11 +260,23 @@ func (g *genericEncoder[Solution, Options]) ContentType() string {
 	return contentTyper.ContentType()
 }
 
+// formattedState holds the part of the formatted plan the statistics are
+// computed from.
 type formattedState struct {
-	Earliness         int                    `json:"earliness"`
-	Lateness          int                    `json:"lateness"`
-	TotalDuration     int                    `json:"total_duration"`
-	NumLifoViolations int                    `json:"num_lifo_violations"`
-	Unassigned        []route.Stop           `json:"unassigned"`
-	Vehicles          []route.PlannedVehicle `json:"vehicles"`
+	Summary *summary `json:"summary"`
+}
+
+type summary struct {
+	VehiclesUsed       int            `json:"vehicles_used"`
+	Assigned           int            `json:"assigned"`
+	Unassigned         int            `json:"unassigned"`
+	UnassignedByType   map[string]int `json:"unassigned_by_type"`
+	InitializationCost float64        `json:"initialization_cost"`
+	BacklogKept        int            `json:"backlog_kept"`
+	DriveTime          int            `json:"drive_time"`
+	WaitTime           int            `json:"wait_time"`
+	Earliness          int            `json:"earliness"`
+	Lateness           int            `json:"lateness"`
+	SoftWindowPenalty  int            `json:"soft_window_penalty"`
+	DeferralPenalty    int            `json:"deferral_penalty"`
 }
@@ -2,17 +2,24 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/nextmv-io/sdk/measure"
 	"github.com/nextmv-io/sdk/route"
 	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/encode"
 	"github.com/nextmv-io/sdk/store"
 )
 
 func main() {
-	err := run.Run(solver)
+	err := run.Run(solver,
+		run.Encode[run.CLIRunnerConfig, input](
+			GenericEncoder[store.Solution, store.Options](encode.JSON()),
+		),
+	)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -28,30 +35,105 @@ type input struct {
 	Stops         []Stop        `json:"stops"`
 	Vehicles      []Vehicle     `json:"vehicles"`
 	Configuration Configuration `json:"configuration"`
+	// Optional matrices keyed by stop and depot IDs, at most one of them. If
+	// neither is given, Haversine distances are used.
+	DurationMatrix *Matrix `json:"duration_matrix,omitempty"`
+	DistanceMatrix *Matrix `json:"distance_matrix,omitempty"`
+	// Optional table of package types that may share a route. Without it,
+	// every route only holds packages of one type.
+	Compatibility *Compatibility `json:"package_type_compatibility,omitempty"`
 }
 
+// Vehicle holds the ID and backlog of a vehicle. All other fields are
+// optional and override the global Configuration for this vehicle only, which
+// allows mixing vehicle types and depots in one fleet.
 type Vehicle struct {
-	ID      string   `json:"id"`
-	Backlog []string `json:"backlog"`
+	ID                 string             `json:"id"`
+	Backlog            []string           `json:"backlog"`
+	Depot              *route.Position    `json:"depot,omitempty"`
+	DepotID            string             `json:"depot_id,omitempty"`
+	Shift              *route.TimeWindow  `json:"driver_shift,omitempty"`
+	Shifts             []route.TimeWindow `json:"driver_shifts,omitempty"`
+	InitializationCost *int               `json:"initialization_cost,omitempty"`
+	Capacity           *int               `json:"capacity,omitempty"`
+	Speed              *int               `json:"speed,omitempty"`
+	Break              *Break             `json:"break,omitempty"`
+}
+
+// configuration returns the given default configuration with the overrides of
+// the vehicle applied. A vehicle with its own depot does not inherit the
+// default depot ID, since it refers to a different location. Likewise, a
+// vehicle with a single shift does not inherit the default daily shifts.
+func (v Vehicle) configuration(c Configuration) Configuration {
+	if v.Depot != nil {
+		c.Depot = *v.Depot
+		c.DepotID = v.DepotID
+	}
+	if v.Shift != nil {
+		c.Shift = *v.Shift
+		c.Shifts = nil
+	}
+	if v.Shifts != nil {
+		c.Shifts = v.Shifts
+	}
+	if v.InitializationCost != nil {
+		c.InitializationCost = *v.InitializationCost
+	}
+	if v.Capacity != nil {
+		c.Capacity = *v.Capacity
+	}
+	if v.Speed != nil {
+		c.Speed = *v.Speed
+	}
+	if v.Break != nil {
+		c.Break = v.Break
+	}
+	return c
 }
 
+// Stop is a parcel to deliver. Quantity, ServiceDuration and
+// UnassignedPenalty are optional and override the quantity, duration and
+// unassigned_penalty of the Configuration for this stop only. A stop with a
+// DueDate must be served before it, on whichever day of the horizon.
 type Stop struct {
 	route.Stop
-	HardWindow route.TimeWindow `json:"hard_window"`
-	Type       string           `json:"package_type"`
+	HardWindow        route.TimeWindow `json:"hard_window"`
+	SoftWindow        *SoftWindow      `json:"soft_window,omitempty"`
+	DueDate           *time.Time       `json:"due_date,omitempty"`
+	Type              string           `json:"package_type"`
+	Quantity          *int             `json:"quantity,omitempty"`
+	ServiceDuration   *int             `json:"service_duration,omitempty"`
+	UnassignedPenalty *int             `json:"unassigned_penalty,omitempty"`
 }
 
+// Configuration holds the defaults for all vehicles and stops. To plan
+// several days at once, Shifts holds one driver shift per day of the horizon
+// instead of the single Shift. Each stop served on a later day than the first
+// adds DeferralPenalty per day of deferral to the value. If Break is given,
+// drivers take a break in every shift according to it.
 type Configuration struct {
-	Depot              route.Position   `json:"depot"`
-	Shift              route.TimeWindow `json:"driver_shift"`
-	InitializationCost int              `json:"initialization_cost"`
-	Capacity           int              `json:"capacity"`
-	Speed              int              `json:"speed"`
-	Quantity           int              `json:"quantity"`
-	Duration           int              `json:"duration"`
-	Penalty            int              `json:"unassigned_penalty"`
-	MaxWait            int              `json:"max_wait"`
-	SolverRunTime      int              `json:"runtime"`
+	Depot              route.Position     `json:"depot"`
+	DepotID            string             `json:"depot_id"`
+	Shift              route.TimeWindow   `json:"driver_shift"`
+	Shifts             []route.TimeWindow `json:"driver_shifts"`
+	InitializationCost int                `json:"initialization_cost"`
+	Capacity           int                `json:"capacity"`
+	Speed              int                `json:"speed"`
+	Quantity           int                `json:"quantity"`
+	Duration           int                `json:"duration"`
+	Penalty            int                `json:"unassigned_penalty"`
+	DeferralPenalty    int                `json:"deferral_penalty"`
+	Break              *Break             `json:"break"`
+	MaxWait            int                `json:"max_wait"`
+	SolverRunTime      int                `json:"runtime"`
+}
+
+// days returns the shift of each day of the planning horizon.
+func (c Configuration) days() []route.TimeWindow {
+	if len(c.Shifts) > 0 {
+		return c.Shifts
+	}
+	return []route.TimeWindow{c.Shift}
 }
 
 // solver takes the input and solver options and constructs a routing solver.
@@ -68,25 +150,36 @@ func solver(i input, opts store.Options) (store.Solver, error) {
 	// First we will create a few helper variables and a set of data structures
 	// which are compatible with the Router engine.
 	var stopCount = len(i.Stops)
-	var vehicleCount = len(i.Vehicles)
 	var maxWait = -1
+	var defaults = i.Configuration
+	if defaults.DepotID == "" {
+		defaults.DepotID = "depot"
+	}
 	if i.Configuration.MaxWait >= 0 {
 		maxWait = i.Configuration.MaxWait
 	}
 
 	stops := make([]route.Stop, stopCount)
-	vehicles := make([]string, vehicleCount)
-	depots := make([]route.Position, vehicleCount)
+	vehicles := make([]string, 0, len(i.Vehicles))
+	vehicleIDs := make([]string, 0, len(i.Vehicles))
+	days := make([]int, 0, len(i.Vehicles))
+	depots := make([]route.Position, 0, len(i.Vehicles))
 	quantities := make([]int, stopCount)
-	capacities := make([]int, vehicleCount)
+	capacities := make([]int, 0, len(i.Vehicles))
 	stopDurations := make([]route.Service, stopCount)
-	shifts := make([]route.TimeWindow, vehicleCount)
+	shifts := make([]route.TimeWindow, 0, len(i.Vehicles))
 	windows := make([]route.Window, stopCount)
 	penalties := make([]int, stopCount)
-	initializationCosts := make([]float64, vehicleCount)
+	initializationCosts := make([]float64, 0, len(i.Vehicles))
+	speeds := make([]float64, 0, len(i.Vehicles))
+	breakRules := make([]*breakRule, 0, len(i.Vehicles))
+	hasBreaks := false
 	backlogs := make([]route.Backlog, 0)
 	points := make([]measure.Point, 0)
+	pointIDs := make([]string, 0)
 	stopTypes := make([]string, stopCount)
+	softWindows := make([]*SoftWindow, stopCount)
+	hasSoftWindows := false
 
 	// Now we need to populate these internal data structures with our input
 	// data.
@@ -95,7 +188,18 @@ func solver(i input, opts store.Options) (store.Solver, error) {
 		quantities[s] = i.Configuration.Quantity
 		penalties[s] = i.Configuration.Penalty
 		points = append(points, measure.Point{stop.Position.Lon, stop.Position.Lat})
+		pointIDs = append(pointIDs, stop.ID)
 		stopDurations[s] = route.Service{ID: stop.ID, Duration: i.Configuration.Duration}
+		// Stops may override the configured defaults.
+		if stop.Quantity != nil {
+			quantities[s] = *stop.Quantity
+		}
+		if stop.UnassignedPenalty != nil {
+			penalties[s] = *stop.UnassignedPenalty
+		}
+		if stop.ServiceDuration != nil {
+			stopDurations[s].Duration = *stop.ServiceDuration
+		}
 		if stop.Type != "" {
 			stopTypes[s] = stop.Type
 		}
@@ -103,43 +207,188 @@ func solver(i input, opts store.Options) (store.Solver, error) {
 		if stop.HardWindow != (route.TimeWindow{}) {
 			windows[s] = route.Window{TimeWindow: stop.HardWindow, MaxWait: maxWait}
 		}
+		// A due date closes the stop's window, whatever day it is served on.
+		if stop.DueDate != nil {
+			if windows[s] == (route.Window{}) {
+				windows[s] = route.Window{MaxWait: maxWait}
+				windows[s].TimeWindow.End = *stop.DueDate
+			} else if stop.DueDate.Before(windows[s].TimeWindow.End) {
+				windows[s].TimeWindow.End = *stop.DueDate
+			}
+		}
+		if stop.SoftWindow != nil {
+			softWindows[s] = stop.SoftWindow
+			hasSoftWindows = true
+		}
 	}
 
+	// Backlogs and plans refer to stops by ID, so we look up their indices.
+	stopIndices := make(map[string]int, stopCount)
+	for s, stop := range stops {
+		stopIndices[stop.ID] = s
+	}
+
+	// Every vehicle starts from the global configuration, which it may
+	// partially override.
+	configs := make([]Configuration, len(i.Vehicles))
+	vehicleCapacities := make([]int, len(i.Vehicles))
+	multiDay := false
+	for v, vehicle := range i.Vehicles {
+		configs[v] = vehicle.configuration(defaults)
+		vehicleCapacities[v] = configs[v].Capacity
+		multiDay = multiDay || len(configs[v].days()) > 1
+	}
+
+	// A vehicle with several daily shifts is planned as one router vehicle per
+	// day, so that each stop is assigned to a day and a vehicle together.
 	for v, vehicle := range i.Vehicles {
-		vehicles[v] = vehicle.ID
-		depots[v] = i.Configuration.Depot
-		capacities[v] = i.Configuration.Capacity
-		shifts[v] = i.Configuration.Shift
-		initializationCosts[v] = float64(i.Configuration.InitializationCost)
-		points = append(points, measure.Point{i.Configuration.Depot.Lon, i.Configuration.Depot.Lat})
-		points = append(points, measure.Point{i.Configuration.Depot.Lon, i.Configuration.Depot.Lat})
-
-		// Vehicles won't always have a backlog, so this are conditional
+		config := configs[v]
+		// A depot must be identifiable to be looked up in a matrix.
+		if config.DepotID == "" && (i.DurationMatrix != nil || i.DistanceMatrix != nil) {
+			return nil, fmt.Errorf("vehicle %q: depot_id is required for its depot when using a matrix", vehicle.ID)
+		}
+		if i.DurationMatrix == nil && config.Speed <= 0 {
+			return nil, fmt.Errorf("vehicle %q: speed must be positive without a duration_matrix", vehicle.ID)
+		}
+
+		first := len(vehicles)
+		for day, shift := range config.days() {
+			id := vehicle.ID
+			if multiDay {
+				id = fmt.Sprintf("%s-day-%d", vehicle.ID, day+1)
+			}
+			vehicles = append(vehicles, id)
+			vehicleIDs = append(vehicleIDs, vehicle.ID)
+			days = append(days, day)
+			depots = append(depots, config.Depot)
+			capacities = append(capacities, config.Capacity)
+			shifts = append(shifts, shift)
+			initializationCosts = append(initializationCosts, float64(config.InitializationCost))
+			speeds = append(speeds, float64(config.Speed))
+			if config.Break != nil {
+				breakRules = append(breakRules, newBreakRule(*config.Break, shift))
+				hasBreaks = true
+			} else {
+				breakRules = append(breakRules, nil)
+			}
+			points = append(points, measure.Point{config.Depot.Lon, config.Depot.Lat})
+			points = append(points, measure.Point{config.Depot.Lon, config.Depot.Lat})
+			pointIDs = append(pointIDs, config.DepotID, config.DepotID)
+		}
+
+		// Vehicles won't always have a backlog, so this are conditional. The
+		// backlog is already loaded, so it is served on the first day.
 		if len(vehicle.Backlog) > 0 {
-			backlogs = append(backlogs, route.Backlog{VehicleID: vehicle.ID, Stops: vehicle.Backlog})
+			backlogs = append(backlogs, route.Backlog{VehicleID: vehicles[first], Stops: vehicle.Backlog})
+		}
+	}
+	vehicleCount := len(vehicles)
+
+	// A stop whose quantity exceeds every capacity can never be served, which
+	// most likely points to an error in the input.
+	maxCapacity := 0
+	for _, capacity := range capacities {
+		if capacity > maxCapacity {
+			maxCapacity = capacity
+		}
+	}
+	for s, quantity := range quantities {
+		if quantity > maxCapacity || -quantity > maxCapacity {
+			return nil, fmt.Errorf(
+				"stop %q: quantity %d does not fit any vehicle capacity (max %d)",
+				i.Stops[s].ID, quantity, maxCapacity,
+			)
 		}
 	}
 
+	// Matrices are keyed by ID, so a stop sharing its ID with a depot would
+	// silently be looked up in the depot's row.
+	if i.DurationMatrix != nil || i.DistanceMatrix != nil {
+		for v, config := range configs {
+			if _, ok := stopIndices[config.DepotID]; ok {
+				return nil, fmt.Errorf(
+					"vehicle %q: depot_id %q is also the ID of a stop",
+					i.Vehicles[v].ID, config.DepotID,
+				)
+			}
+		}
+	}
+
+	// Backlogs are validated upfront, since a faulty backlog would otherwise
+	// only fail deep inside the router or silently yield a bad plan.
+	if err := validateBacklogs(i.Vehicles, stopIndices, quantities, vehicleCapacities); err != nil {
+		return nil, err
+	}
+
 	// Since we want to explicitly optimize for duration rather than distance, we
-	// will create a duration measure. This one uses Haversine, but this is
-	// easily adaptable to accept a matrix input built from your chosen provider
-	// of distance & duration data. More information about available measures is
-	// available [in our docs](https://www.nextmv.io/docs/how-to-guides/router#measures---cost).
-	distance := measure.HaversineByPoint()
-	distanceIndexed := route.Indexed(distance, points)
-	timeMeasures := make([]route.ByIndex, vehicleCount)
+	// will create a duration measure. If the input holds a duration matrix built
+	// from your chosen provider of distance & duration data, it is used
+	// directly. Otherwise distances are taken from the distance matrix or
+	// estimated with Haversine and converted to durations using the speed. More
+	// information about available measures is available [in our
+	// docs](https://www.nextmv.io/docs/how-to-guides/router#measures---cost).
+	if i.DurationMatrix != nil && i.DistanceMatrix != nil {
+		return nil, errors.New(
+			"duration_matrix and distance_matrix cannot be given together",
+		)
+	}
+	var durations, distances [][]float64
+	if i.DurationMatrix != nil {
+		var err error
+		if durations, err = i.DurationMatrix.indexed(pointIDs); err != nil {
+			return nil, fmt.Errorf("duration_matrix: %w", err)
+		}
+	}
+	if i.DistanceMatrix != nil {
+		var err error
+		if distances, err = i.DistanceMatrix.indexed(pointIDs); err != nil {
+			return nil, fmt.Errorf("distance_matrix: %w", err)
+		}
+	}
 
+	timeMeasures := make([]route.ByIndex, vehicleCount)
 	for m := range timeMeasures {
-		timeMeasures[m] = measure.Scale(distanceIndexed, 1.0/float64(i.Configuration.Speed))
+		timeMeasures[m] = timeMeasure(
+			durations,
+			distances,
+			points,
+			speeds[m],
+		)
+	}
+
+	// We need to create the custom type needed for our custom constraint
+	// interface. Package types are indexed so that their compatibility can be
+	// looked up quickly.
+	types, compatible, err := packageTypes(stopTypes, i.Compatibility)
+	if err != nil {
+		return nil, err
 	}
+	typeConstraint := newCustomConstraint(types, compatible)
 
-	// We need to create the custom type needed for our custom constraint interface.
-	typeConstraint := CustomConstraint{types: stopTypes}
+	// Plans and constraints refer to vehicles by ID, so we look up their
+	// indices.
+	vehicleIndices := make(map[string]int, vehicleCount)
+	for v, vehicle := range vehicles {
+		vehicleIndices[vehicle] = v
+	}
+
+	// Breaks are checked in a second constraint, which has to be combined with
+	// the first one. It needs to know until when each stop must be served.
+	stopWindowEnds := windowEnds(windows)
+	var constraint route.VehicleConstraint = typeConstraint
+	if hasBreaks {
+		constraint = vehicleConstraints{
+			typeConstraint,
+			BreakConstraint{
+				vehicles:   vehicleIndices,
+				rules:      breakRules,
+				windowEnds: stopWindowEnds,
+			},
+		}
+	}
 
 	// Now we define our router with the constraints and options we've selected.
-	router, err := route.NewRouter(
-		stops,
-		vehicles,
+	options := []route.Option{
 		route.Starts(depots),
 		route.Ends(depots),
 		route.Services(stopDurations),
@@ -151,12 +400,53 @@ func solver(i input, opts store.Options) (store.Solver, error) {
 		route.Windows(windows),
 		route.ValueFunctionMeasures(timeMeasures),
 		route.TravelTimeMeasures(timeMeasures),
-		route.Constraint(typeConstraint, vehicles),
-	)
+		route.Constraint(constraint, vehicles),
+	}
+
+	// Soft windows and deferrals are penalized in a custom value function,
+	// which is only needed if the input makes use of them.
+	deferralPenalty := 0
+	if multiDay {
+		deferralPenalty = i.Configuration.DeferralPenalty
+	}
+	if hasSoftWindows || deferralPenalty != 0 {
+		v := vehicleData{
+			vehicles:            vehicleIndices,
+			measures:            timeMeasures,
+			initializationCosts: initializationCosts,
+			softWindows:         softWindows,
+			days:                days,
+			deferralPenalty:     deferralPenalty,
+			breakRules:          breakRules,
+			windowEnds:          stopWindowEnds,
+		}
+		p := planData{penalties: penalties}
+		options = append(options, route.Update(v, p))
+	}
+
+	router, err := route.NewRouter(stops, vehicles, options...)
 	if err != nil {
 		return nil, err
 	}
 
+	// The custom format adds KPIs per vehicle and for the whole fleet.
+	router.Format(outputFormat(formatData{
+		stops:               stopIndices,
+		quantities:          quantities,
+		types:               stopTypes,
+		softWindows:         softWindows,
+		vehicles:            vehicleIndices,
+		capacities:          capacities,
+		initializationCosts: initializationCosts,
+		backlogs:            backlogs,
+		vehicleIDs:          vehicleIDs,
+		days:                days,
+		multiDay:            multiDay,
+		deferralPenalty:     deferralPenalty,
+		breakRules:          breakRules,
+		windows:             windows,
+	}))
+
 	// You can also fix solver options like the expansion limit below.
 	opts.Diagram.Expansion.Limit = 1
 	// A duration limit of 0 is treated as infinity. For cloud runs you need to
@@ -176,12 +466,36 @@ func solver(i input, opts store.Options) (store.Solver, error) {
 // CustomConstraint is a custom type that implements Violated to fulfill the
 // VehicleConstraint interface.
 type CustomConstraint struct {
-	types []string
+	// types holds the package type index of each stop, -1 for wildcards.
+	types []int
+	// conflicts holds for each package type a bitset of the types it may not
+	// share a route with.
+	conflicts [][]uint64
+}
+
+// newCustomConstraint returns the constraint for the given package types of
+// the stops and the compatibility of the types.
+func newCustomConstraint(types []int, compatible [][]bool) CustomConstraint {
+	words := (len(compatible) + 63) / 64
+	conflicts := make([][]uint64, len(compatible))
+	for t := range compatible {
+		conflicts[t] = make([]uint64, words)
+		for u, ok := range compatible[t] {
+			if !ok {
+				conflicts[t][u/64] |= 1 << (u % 64)
+			}
+		}
+	}
+	return CustomConstraint{types: types, conflicts: conflicts}
 }
 
 // Violated the method that must be implemented to be a used as a
-// VehicleConstraint. This checks to ensure only packages of the same type are
-// on a route.
+// VehicleConstraint. This checks to ensure only packages of compatible types
+// are on a route. The whole route is checked on every call: the router may
+// remove stops from a route as well as insert them, so types seen on an
+// earlier version of the route cannot be carried over. The types on the route
+// are kept in a bitset, so each stop is checked with a few word operations
+// and, for up to 256 types, without allocating.
 func (c CustomConstraint) Violated(
 	vehicle route.PartialVehicle,
 ) (route.VehicleConstraint, bool) {
@@ -192,19 +506,39 @@ func (c CustomConstraint) Violated(
 		return c, false
 	}
 
-	// Omit the start and end locations of the vehicle and the first stop.
-	locations := route[2 : len(vehicle.Route())-1]
-
-	// Get the label of the first stop assigned to the route.
-	label := c.types[route[1]]
-	for _, location := range locations {
-		// If the labels don't match, the constraint is violated.
-		if len(c.types) > 1 {
-			if label != c.types[location] {
+	var buffer [4]uint64
+	var onRoute []uint64
+	if words := (len(c.conflicts) + 63) / 64; words <= len(buffer) {
+		onRoute = buffer[:words]
+	} else {
+		onRoute = make([]uint64, words)
+	}
+	for _, location := range route {
+		// Wildcards and the start and end locations of the vehicle are
+		// compatible with everything.
+		t := c.typeOf(location)
+		if t < 0 {
+			continue
+		}
+		// If the type conflicts with any type on the route, including
+		// itself, the constraint is violated.
+		for w, conflicts := range c.conflicts[t] {
+			if conflicts&onRoute[w] != 0 {
 				return c, true
 			}
 		}
+		onRoute[t/64] |= 1 << (t % 64)
 	}
 
 	return c, false
 }
+
+// typeOf returns the package type index of a location. Locations past the
+// stops are the start and end locations of vehicles, which carry no package
+// and are reported like wildcards.
+func (c CustomConstraint) typeOf(location int) int {
+	if location < 0 || location >= len(c.types) {
+		return -1
+	}
+	return c.types[location]
+}
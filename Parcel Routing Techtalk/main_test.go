@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// partialVehicle is a synthetic route.PartialVehicle that only holds a route
+// and its times.
+type partialVehicle struct {
+	route []int
+	times route.Times
+}
+
+func (v partialVehicle) ID() string                    { return "vehicle" }
+func (v partialVehicle) Updater() route.VehicleUpdater { return nil }
+func (v partialVehicle) Route() []int                  { return v.route }
+func (v partialVehicle) Value() int                    { return 0 }
+func (v partialVehicle) Times() route.Times            { return v.times }
+
+func TestCustomConstraintViolated(t *testing.T) {
+	// Stops 0 to 4 are followed by the start (5) and end (6) location of the
+	// vehicle.
+	types := []string{"standard", "fragile", "hazmat", "food", ""}
+	wildcard := false
+
+	tests := []struct {
+		name          string
+		compatibility *Compatibility
+		route         []int
+		want          bool
+	}{
+		{
+			name:  "empty route",
+			route: []int{5, 6},
+		},
+		{
+			name:  "single stop",
+			route: []int{5, 2, 6},
+		},
+		{
+			name:  "single untyped stop",
+			route: []int{5, 4, 6},
+		},
+		{
+			name:  "same type",
+			route: []int{5, 0, 0, 6},
+		},
+		{
+			name:  "different types without table",
+			route: []int{5, 0, 1, 6},
+			want:  true,
+		},
+		{
+			name:  "untyped stops are wildcards",
+			route: []int{5, 4, 0, 4, 6},
+		},
+		{
+			name: "untyped stops are their own type",
+			compatibility: &Compatibility{
+				UntypedWildcard: &wildcard,
+			},
+			route: []int{5, 4, 0, 6},
+			want:  true,
+		},
+		{
+			name: "untyped stops allowed with a type",
+			compatibility: &Compatibility{
+				Allowed:         [][2]string{{"", "standard"}},
+				UntypedWildcard: &wildcard,
+			},
+			route: []int{5, 0, 4, 6},
+		},
+		{
+			name: "allowed pair in any order",
+			compatibility: &Compatibility{
+				Allowed: [][2]string{{"fragile", "standard"}},
+			},
+			route: []int{5, 0, 1, 0, 6},
+		},
+		{
+			name: "allowed pair with a third type",
+			compatibility: &Compatibility{
+				Allowed: [][2]string{{"standard", "fragile"}},
+			},
+			route: []int{5, 0, 1, 3, 6},
+			want:  true,
+		},
+		{
+			name: "unlisted pair allowed",
+			compatibility: &Compatibility{
+				Forbidden:     [][2]string{{"hazmat", "food"}},
+				AllowUnlisted: true,
+			},
+			route: []int{5, 0, 1, 2, 6},
+		},
+		{
+			name: "forbidden pair",
+			compatibility: &Compatibility{
+				Forbidden:     [][2]string{{"hazmat", "food"}},
+				AllowUnlisted: true,
+			},
+			route: []int{5, 0, 3, 1, 2, 6},
+			want:  true,
+		},
+		{
+			name: "forbidden same type",
+			compatibility: &Compatibility{
+				Forbidden: [][2]string{{"hazmat", "hazmat"}},
+			},
+			route: []int{5, 2, 4, 2, 6},
+			want:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indices, compatible, err := packageTypes(types, test.compatibility)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := newCustomConstraint(indices, compatible)
+			_, got := c.Violated(partialVehicle{route: test.route})
+			if got != test.want {
+				t.Errorf("Violated(%v) = %v, want %v", test.route, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPackageTypesConflict(t *testing.T) {
+	_, _, err := packageTypes(
+		[]string{"standard", "fragile"},
+		&Compatibility{
+			Allowed:   [][2]string{{"standard", "fragile"}},
+			Forbidden: [][2]string{{"fragile", "standard"}},
+		},
+	)
+	if err == nil {
+		t.Error("expected an error for a pair that is allowed and forbidden")
+	}
+}
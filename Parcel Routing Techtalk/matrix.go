@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nextmv-io/sdk/measure"
+	"github.com/nextmv-io/sdk/route"
+)
+
+// Matrix is a full matrix of travel costs between locations, e.g. as returned
+// by a routing provider. Rows and columns of Values are keyed by IDs, which
+// must contain the ID of every stop and of every depot. The order of IDs does
+// not matter and additional locations are ignored.
+type Matrix struct {
+	IDs    []string    `json:"ids"`
+	Values [][]float64 `json:"values"`
+}
+
+// indexed returns the arcs of the matrix ordered by the given location IDs.
+// The IDs correspond one to one to the points passed to the router, so the
+// returned matrix always has the dimensions of those points. An error is
+// returned if the matrix is malformed or a location is missing.
+func (m Matrix) indexed(ids []string) ([][]float64, error) {
+	if len(m.Values) != len(m.IDs) {
+		return nil, fmt.Errorf(
+			"matrix has %d rows but %d ids", len(m.Values), len(m.IDs),
+		)
+	}
+
+	index := make(map[string]int, len(m.IDs))
+	for r, id := range m.IDs {
+		if len(m.Values[r]) != len(m.IDs) {
+			return nil, fmt.Errorf(
+				"matrix row %q has %d columns but %d are expected",
+				id, len(m.Values[r]), len(m.IDs),
+			)
+		}
+		if _, ok := index[id]; ok {
+			return nil, fmt.Errorf("matrix id %q is not unique", id)
+		}
+		index[id] = r
+	}
+
+	rows := make([]int, len(ids))
+	for p, id := range ids {
+		r, ok := index[id]
+		if !ok {
+			return nil, fmt.Errorf("matrix is missing location %q", id)
+		}
+		rows[p] = r
+	}
+
+	arcs := make([][]float64, len(ids))
+	for from, r := range rows {
+		arcs[from] = make([]float64, len(ids))
+		for to, c := range rows {
+			if m.Values[r][c] < 0 {
+				return nil, fmt.Errorf(
+					"matrix value from %q to %q is negative", ids[from], ids[to],
+				)
+			}
+			arcs[from][to] = m.Values[r][c]
+		}
+	}
+
+	return arcs, nil
+}
+
+// timeMeasure returns the measure used both for the value function and for
+// travel times. A duration matrix is used as is. Otherwise distances are
+// taken from the distance matrix, falling back to Haversine distances between
+// the points, and converted to durations with the given speed.
+func timeMeasure(
+	durations [][]float64,
+	distances [][]float64,
+	points []measure.Point,
+	speed float64,
+) route.ByIndex {
+	if durations != nil {
+		return route.Matrix(durations)
+	}
+
+	var distance route.ByIndex
+	if distances != nil {
+		distance = route.Matrix(distances)
+	} else {
+		distance = route.Indexed(measure.HaversineByPoint(), points)
+	}
+
+	return measure.Scale(distance, 1.0/speed)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatrixIndexed(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix Matrix
+		ids    []string
+		want   [][]float64
+		err    string
+	}{
+		{
+			name: "reordered and extra locations",
+			matrix: Matrix{
+				IDs: []string{"a", "b", "c"},
+				Values: [][]float64{
+					{0, 1, 2},
+					{3, 0, 4},
+					{5, 6, 0},
+				},
+			},
+			ids:  []string{"c", "a"},
+			want: [][]float64{{0, 5}, {2, 0}},
+		},
+		{
+			name: "row and id count mismatch",
+			matrix: Matrix{
+				IDs:    []string{"a", "b"},
+				Values: [][]float64{{0, 1}},
+			},
+			ids: []string{"a"},
+			err: "matrix has 1 rows but 2 ids",
+		},
+		{
+			name: "ragged row",
+			matrix: Matrix{
+				IDs:    []string{"a", "b"},
+				Values: [][]float64{{0, 1}, {1}},
+			},
+			ids: []string{"a"},
+			err: `matrix row "b" has 1 columns but 2 are expected`,
+		},
+		{
+			name: "duplicate id",
+			matrix: Matrix{
+				IDs:    []string{"a", "a"},
+				Values: [][]float64{{0, 1}, {1, 0}},
+			},
+			ids: []string{"a"},
+			err: `matrix id "a" is not unique`,
+		},
+		{
+			name: "missing location",
+			matrix: Matrix{
+				IDs:    []string{"a", "b"},
+				Values: [][]float64{{0, 1}, {1, 0}},
+			},
+			ids: []string{"a", "depot"},
+			err: `matrix is missing location "depot"`,
+		},
+		{
+			name: "negative value",
+			matrix: Matrix{
+				IDs:    []string{"a", "b"},
+				Values: [][]float64{{0, 1}, {-1, 0}},
+			},
+			ids: []string{"a", "b"},
+			err: `matrix value from "b" to "a" is negative`,
+		},
+		{
+			name: "negative value of an unused location",
+			matrix: Matrix{
+				IDs:    []string{"a", "b"},
+				Values: [][]float64{{0, 1}, {-1, 0}},
+			},
+			ids:  []string{"a"},
+			want: [][]float64{{0}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.matrix.indexed(test.ids)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
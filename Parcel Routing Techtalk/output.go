@@ -0,0 +1,282 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// formatData holds the input data needed to compute the KPIs of a plan. Stops
+// and vehicles are looked up by ID, everything else by index.
+type formatData struct {
+	stops               map[string]int
+	quantities          []int
+	types               []string
+	softWindows         []*SoftWindow
+	vehicles            map[string]int
+	capacities          []int
+	initializationCosts []float64
+	backlogs            []route.Backlog
+	vehicleIDs          []string
+	days                []int
+	multiDay            bool
+	deferralPenalty     int
+	breakRules          []*breakRule
+	windows             []route.Window
+}
+
+// outputFormat returns a custom format for the plan. Next to the planned
+// routes it reports KPIs per vehicle and a summary of the whole fleet. All
+// times are given in seconds. When planning several days, the routes are
+// grouped by day. A driver's break is added to the route as a pseudo-stop and
+// the times of the stops after it are delayed accordingly.
+func outputFormat(d formatData) func(p *route.Plan) any {
+	// Remember the backlog vehicle of each stop.
+	backlogVehicles := make(map[string]string)
+	for _, backlog := range d.backlogs {
+		for _, stop := range backlog.Stops {
+			backlogVehicles[stop] = backlog.VehicleID
+		}
+	}
+
+	// Remember until when each stop must be served.
+	stopWindowEnds := windowEnds(d.windows)
+
+	return func(p *route.Plan) any {
+		output := make(map[string]any)
+		vehicles := make([]any, len(p.Vehicles))
+		var totalEarliness, totalLateness, totalPenalty int
+		var totalDriveTime, totalWaitTime, usedVehicles, assigned int
+		var backlogKept, totalDeferralPenalty int
+		byDay := map[int][]any{}
+		var initializationCost float64
+		for v, vehicle := range p.Vehicles {
+			index, known := d.vehicles[vehicle.ID]
+
+			// The break is placed with the same logic that the constraint
+			// uses, which yields the times of the route including it.
+			times, locations := plannedTimes(vehicle.Route, d.stops)
+			planned := schedule{
+				position:   -1,
+				arrivals:   times.EstimatedArrival,
+				services:   times.EstimatedServiceStart,
+				departures: times.EstimatedDeparture,
+			}
+			if known && d.breakRules[index] != nil && len(vehicle.Route) > 2 {
+				ends := routeWindowEnds(locations, stopWindowEnds)
+				if scheduled, ok := d.breakRules[index].schedule(times, ends); ok {
+					planned = scheduled
+				}
+			}
+
+			route := make([]any, 0, len(vehicle.Route)+1)
+			var stops, parcels, startLoad, change, maxChange int
+			var driveTime, waitTime int
+			types := []string{}
+			for i, stop := range vehicle.Route {
+				location := time.UTC
+				if stop.EstimatedArrival != nil {
+					location = stop.EstimatedArrival.Location()
+				}
+				plannedStop := map[string]any{
+					"id":                  stop.ID,
+					"position":            stop.Position,
+					"estimated_arrival":   unixTime(planned.arrivals[i], location),
+					"estimated_departure": unixTime(planned.departures[i], location),
+					"estimated_service":   unixTime(planned.services[i], location),
+				}
+				route = append(route, plannedStop)
+				if i == planned.position {
+					route = append(route, map[string]any{
+						"id":                  "break",
+						"position":            stop.Position,
+						"estimated_arrival":   unixTime(planned.start, location),
+						"estimated_departure": unixTime(planned.start+d.breakRules[index].duration, location),
+						"break":               true,
+					})
+				}
+
+				// Driving happens between the departure from the previous
+				// location and the arrival at this one, waiting between the
+				// arrival and the start of service.
+				if i > 0 {
+					driveTime += planned.arrivals[i] - planned.departures[i-1]
+				}
+				waitTime += planned.services[i] - planned.arrivals[i]
+
+				// The vehicle's start and end location carry no parcels.
+				s, ok := d.stops[stop.ID]
+				if !ok || i == 0 || i == len(vehicle.Route)-1 {
+					continue
+				}
+				stops++
+
+				// Deliveries are loaded at the start and pick-ups along the
+				// way, so the peak load is the start load plus the largest
+				// change in load seen on the route.
+				quantity := d.quantities[s]
+				if quantity < 0 {
+					parcels -= quantity
+					startLoad -= quantity
+				} else {
+					parcels += quantity
+				}
+				change += quantity
+				if change > maxChange {
+					maxChange = change
+				}
+				// Mark stops that were kept on their backlog vehicle.
+				kept := backlogVehicles[stop.ID] == vehicle.ID
+				plannedStop["backlog"] = kept
+				if kept {
+					backlogKept++
+				}
+				if t := d.types[s]; t != "" && !contains(types, t) {
+					types = append(types, t)
+				}
+
+				// Only stops with a soft window can deviate from it.
+				window := d.softWindows[s]
+				if window != nil {
+					earliness, lateness, penalty := window.deviation(
+						planned.services[i],
+					)
+					plannedStop["earliness"] = earliness
+					plannedStop["lateness"] = lateness
+					totalEarliness += earliness
+					totalLateness += lateness
+					totalPenalty += penalty
+				}
+			}
+
+			// The break delays the end of the route.
+			last := len(vehicle.Route) - 1
+			routeDuration := vehicle.RouteDuration
+			if last >= 0 {
+				routeDuration += planned.arrivals[last] - times.EstimatedArrival[last]
+			}
+
+			capacity, day, id := 0, 0, vehicle.ID
+			if known {
+				capacity = d.capacities[index]
+				day, id = d.days[index], d.vehicleIDs[index]
+				if stops > 0 {
+					initializationCost += d.initializationCosts[index]
+				}
+			}
+			deferralPenalty := stops * day * d.deferralPenalty
+			totalDeferralPenalty += deferralPenalty
+			if stops > 0 {
+				usedVehicles++
+			}
+			assigned += stops
+			totalDriveTime += driveTime
+			totalWaitTime += waitTime
+
+			plannedVehicle := map[string]any{
+				"id":             id,
+				"route":          route,
+				"route_duration": routeDuration,
+				"stops":          stops,
+				"parcels":        parcels,
+				"load":           startLoad + maxChange,
+				"capacity":       capacity,
+				"drive_time":     driveTime,
+				"wait_time":      waitTime,
+				"package_types":  types,
+			}
+			if d.multiDay {
+				plannedVehicle["day"] = day + 1
+				plannedVehicle["deferral_penalty"] = deferralPenalty
+				byDay[day] = append(byDay[day], plannedVehicle)
+			}
+			vehicles[v] = plannedVehicle
+		}
+
+		unassignedByType := make(map[string]int)
+		for _, stop := range p.Unassigned {
+			if s, ok := d.stops[stop.ID]; ok {
+				unassignedByType[d.types[s]]++
+			}
+		}
+
+		output["unassigned"] = p.Unassigned
+		if d.multiDay {
+			days := make([]any, 0, len(byDay))
+			for day := 0; len(days) < len(byDay); day++ {
+				if dayVehicles, ok := byDay[day]; ok {
+					days = append(days, map[string]any{
+						"day":      day + 1,
+						"vehicles": dayVehicles,
+					})
+				}
+			}
+			output["days"] = days
+		} else {
+			output["vehicles"] = vehicles
+		}
+		output["summary"] = map[string]any{
+			"vehicles_used":       usedVehicles,
+			"assigned":            assigned,
+			"unassigned":          len(p.Unassigned),
+			"unassigned_by_type":  unassignedByType,
+			"initialization_cost": initializationCost,
+			"backlog_kept":        backlogKept,
+			"drive_time":          totalDriveTime,
+			"wait_time":           totalWaitTime,
+			"earliness":           totalEarliness,
+			"lateness":            totalLateness,
+			"soft_window_penalty": totalPenalty,
+			"deferral_penalty":    totalDeferralPenalty,
+		}
+
+		return output
+	}
+}
+
+// contains returns whether the slice holds the given value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// plannedTimes returns the times of a planned route as unix times, together
+// with the location index of each stop. Missing times are set to 0 and the
+// start and end locations of the vehicle get an index past the stops.
+func plannedTimes(
+	plannedStops []route.PlannedStop,
+	stops map[string]int,
+) (route.Times, []int) {
+	times := route.Times{
+		EstimatedArrival:      make([]int, len(plannedStops)),
+		EstimatedServiceStart: make([]int, len(plannedStops)),
+		EstimatedDeparture:    make([]int, len(plannedStops)),
+	}
+	locations := make([]int, len(plannedStops))
+	for i, stop := range plannedStops {
+		if stop.EstimatedArrival != nil {
+			times.EstimatedArrival[i] = int(stop.EstimatedArrival.Unix())
+		}
+		if stop.EstimatedService != nil {
+			times.EstimatedServiceStart[i] = int(stop.EstimatedService.Unix())
+		}
+		if stop.EstimatedDeparture != nil {
+			times.EstimatedDeparture[i] = int(stop.EstimatedDeparture.Unix())
+		}
+		location, ok := stops[stop.ID]
+		if !ok || i == 0 || i == len(plannedStops)-1 {
+			location = len(stops)
+		}
+		locations[i] = location
+	}
+	return times, locations
+}
+
+// unixTime converts a unix time to a time in the given location.
+func unixTime(seconds int, location *time.Location) time.Time {
+	return time.Unix(int64(seconds), 0).In(location)
+}
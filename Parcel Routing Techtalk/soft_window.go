@@ -0,0 +1,123 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// SoftWindow is a time window a stop should be served in. In contrast to a
+// hard window, serving the stop outside of it is allowed, but each second of
+// earliness or lateness is penalized in the value function.
+type SoftWindow struct {
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	EarlinessPenalty int       `json:"earliness_penalty"`
+	LatenessPenalty  int       `json:"lateness_penalty"`
+}
+
+// deviation returns the earliness and lateness in seconds of a service
+// starting at the given unix time, together with the resulting penalty.
+func (w SoftWindow) deviation(service int) (earliness, lateness, penalty int) {
+	if start := int(w.Start.Unix()); service < start {
+		earliness = start - service
+	}
+	if end := int(w.End.Unix()); service > end {
+		lateness = service - end
+	}
+	penalty = earliness*w.EarlinessPenalty + lateness*w.LatenessPenalty
+	return earliness, lateness, penalty
+}
+
+// vehicleData implements the route.VehicleUpdater interface. It replaces the
+// default vehicle value with the travel time of the route plus the
+// initialization cost of the vehicle, the soft window penalties of its stops
+// and the penalty for deferring its stops to the vehicle's day. Soft windows
+// are checked against the times with the driver's break, as in the output.
+type vehicleData struct {
+	vehicles            map[string]int
+	measures            []route.ByIndex
+	initializationCosts []float64
+	softWindows         []*SoftWindow
+	days                []int
+	deferralPenalty     int
+	// breakRules holds the break rule of each vehicle, nil if it has none.
+	breakRules []*breakRule
+	// windowEnds holds the latest service start of each stop, 0 if none.
+	windowEnds []int
+}
+
+func (v vehicleData) Update(
+	s route.PartialVehicle,
+) (route.VehicleUpdater, int, bool) {
+	r := s.Route()
+	// An unused vehicle has no cost.
+	if len(r) <= 2 {
+		return v, 0, true
+	}
+
+	vehicle := v.vehicles[s.ID()]
+	value := v.initializationCosts[vehicle]
+	for i := 1; i < len(r); i++ {
+		value += v.measures[vehicle].Cost(r[i-1], r[i])
+	}
+
+	// Loop over all stops in the route and add the penalty for starting
+	// service outside of their soft window.
+	times := s.Times()
+	services := times.EstimatedServiceStart
+	// The break delays the service of the stops after it.
+	if rule := v.breakRules[vehicle]; rule != nil {
+		ends := routeWindowEnds(r, v.windowEnds)
+		if scheduled, ok := rule.schedule(times, ends); ok {
+			services = scheduled.services
+		}
+	}
+	penalty := 0
+	for i, location := range r {
+		if location < len(v.softWindows) && v.softWindows[location] != nil {
+			_, _, p := v.softWindows[location].deviation(services[i])
+			penalty += p
+		}
+	}
+
+	// Every stop served on a later day is penalized once per day deferred.
+	penalty += (len(r) - 2) * v.days[vehicle] * v.deferralPenalty
+
+	return v, int(value) + penalty, true
+}
+
+// planData implements the route.PlanUpdater interface. The value of the plan
+// is the sum of the vehicle values and the penalties of unassigned stops.
+type planData struct {
+	penalties     []int
+	vehicleValues map[string]int
+	vehiclesValue int
+}
+
+func (d planData) Update(
+	p route.PartialPlan,
+	vehicles []route.PartialVehicle,
+) (route.PlanUpdater, int, bool) {
+	// Perform a safe copy of the vehicle values map.
+	values := make(map[string]int, len(d.vehicleValues))
+	for vehicleID, i := range d.vehicleValues {
+		values[vehicleID] = i
+	}
+	d.vehicleValues = values
+
+	// Update the values for the vehicles that changed.
+	for _, vehicle := range vehicles {
+		vehicleID := vehicle.ID()
+		d.vehiclesValue -= d.vehicleValues[vehicleID]
+		d.vehicleValues[vehicleID] = vehicle.Value()
+		d.vehiclesValue += d.vehicleValues[vehicleID]
+	}
+
+	value := d.vehiclesValue
+	for _, s := range p.Unassigned().Slice() {
+		value += d.penalties[s]
+	}
+
+	return d, value, true
+}
@@ -2,7 +2,10 @@ module example.com/your_project/routing
 
 go 1.19
 
-require github.com/nextmv-io/sdk v0.23.0
+require (
+	github.com/klauspost/compress v1.16.7
+	github.com/nextmv-io/sdk v0.23.0
+)
 
 require (
 	github.com/google/uuid v1.3.0 // indirect
@@ -0,0 +1,65 @@
+package routing
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// File extensions of the supported compression formats.
+const (
+	gzipExtension = ".gz"
+	zstdExtension = ".zst"
+)
+
+// Magic bytes at the start of compressed data.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressWriter wraps the writer to compress its data according to the
+// extension of the path. It returns the path without that extension. The
+// returned writer is nil if the path has no compression extension, otherwise
+// it must be closed to write the trailer of the compressed data.
+func compressWriter(
+	writer io.Writer,
+	path string,
+) (io.WriteCloser, string, error) {
+	switch {
+	case strings.HasSuffix(path, gzipExtension):
+		return gzip.NewWriter(writer), strings.TrimSuffix(path, gzipExtension), nil
+	case strings.HasSuffix(path, zstdExtension):
+		encoder, err := zstd.NewWriter(writer)
+		if err != nil {
+			return nil, path, err
+		}
+		return encoder, strings.TrimSuffix(path, zstdExtension), nil
+	}
+	return nil, path, nil
+}
+
+// decompressReader wraps the reader to decompress its data. The compression
+// format is detected by the extension of the path or, if that is unknown, by
+// the magic bytes at the start of the data. The returned reader must be closed
+// to release its resources.
+func decompressReader(reader io.Reader, path string) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(reader)
+	magic, _ := buffered.Peek(len(zstdMagic))
+
+	switch {
+	case strings.HasSuffix(path, gzipExtension), bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(buffered)
+	case strings.HasSuffix(path, zstdExtension), bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return io.NopCloser(buffered), nil
+}
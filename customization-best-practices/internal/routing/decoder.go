@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/decode"
+)
+
+// GenericDecoder returns a new Decoder that decodes the input using the given
+// decoder. It is the counterpart of GenericEncoder: inputs compressed with
+// gzip or zstd are decompressed first.
+func GenericDecoder[Input any](decoder decode.Decoder) run.Decoder[Input] {
+	dec := genericDecoder[Input]{decoder}
+	return dec.Decode
+}
+
+type genericDecoder[Input any] struct {
+	decoder decode.Decoder
+}
+
+// Decode decodes the input from the reader, which needs to be an io.Reader.
+// The compression is detected by the extension of the input file, if the
+// reader is a file, or else by the magic bytes at the start of the input.
+func (g *genericDecoder[Input]) Decode(
+	_ context.Context,
+	reader any,
+) (input Input, err error) {
+	closer, ok := reader.(io.Closer)
+	if ok {
+		defer func() {
+			tempErr := closer.Close()
+			// the first error is the most important
+			if err == nil {
+				err = tempErr
+			}
+		}()
+	}
+
+	ioReader, ok := reader.(io.Reader)
+	if !ok {
+		err = errors.New("decoder is not compatible with configured IOProducer")
+		return input, err
+	}
+
+	path := ""
+	if file, ok := reader.(interface{ Name() string }); ok {
+		path = file.Name()
+	}
+
+	decompressed, err := decompressReader(ioReader, path)
+	if err != nil {
+		return input, err
+	}
+	defer func() {
+		tempErr := decompressed.Close()
+		if err == nil {
+			err = tempErr
+		}
+	}()
+
+	err = g.decoder.Decode(decompressed, &input)
+	return input, err
+}
@@ -0,0 +1,464 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nextmv-io/sdk"
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/encode"
+)
+
+type output struct {
+	Statistics statisticsIn `json:"statistics"`
+}
+
+// statisticsIn of the search.
+type statisticsIn struct {
+	Time Time `json:"time"`
+	// Value of the store. Nil when using a Satisfier.
+	Value *int `json:"value,omitempty"`
+}
+
+// Time needed.
+type Time struct {
+	Start   time.Time `json:"start"`
+	Elapsed duration  `json:"elapsed"`
+}
+
+type duration struct {
+	time.Duration
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		d.Duration = time.Duration(value)
+		return nil
+	case string:
+		var err error
+		d.Duration, err = time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		return nil
+	default:
+		return errors.New("invalid duration")
+	}
+}
+
+type statisticsOut struct {
+	Schema string        `json:"schema"`
+	Result result        `json:"result"`
+	Series []seriesPoint `json:"series,omitempty"`
+}
+
+// seriesPoint describes a single solution found during the search.
+type seriesPoint struct {
+	Value        float64 `json:"value"`
+	Elapsed      float64 `json:"elapsed"`
+	Assigned     int     `json:"assigned"`
+	VehiclesUsed int     `json:"vehicles_used"`
+}
+
+// SeriesStatistics is implemented by custom statistics that report the
+// assigned stops and used vehicles of a solution for the series.
+type SeriesStatistics interface {
+	AssignedStops() int
+	VehiclesUsed() int
+}
+
+type result struct {
+	Value   float64 `json:"value"`
+	Elapsed float64 `json:"elapsed"`
+	Custom  any     `json:"custom"`
+}
+
+type version struct {
+	Sdk string `json:"sdk"`
+}
+type meta[Options, Solution any] struct {
+	Version    version       `json:"version"`
+	Options    Options       `json:"options"`
+	Solutions  []Solution    `json:"solutions"`
+	Statistics statisticsOut `json:"statistics"`
+}
+
+// streamedMeta is a single line of streamed output.
+type streamedMeta[Options, Solution any] struct {
+	Version    version        `json:"version"`
+	Options    Options        `json:"options"`
+	Solution   Solution       `json:"solution"`
+	Statistics *statisticsOut `json:"statistics,omitempty"`
+}
+
+// StatisticsExtractor maps a solution to the custom statistics of a model.
+// Nil statistics mean that the solution holds nothing to report.
+type StatisticsExtractor[Solution any] interface {
+	Statistics(Solution) (any, error)
+}
+
+// StatisticsFunc is a function that implements StatisticsExtractor.
+type StatisticsFunc[Solution any] func(Solution) (any, error)
+
+// Statistics calls f(solution).
+func (f StatisticsFunc[Solution]) Statistics(solution Solution) (any, error) {
+	return f(solution)
+}
+
+// StoreStatistics returns a StatisticsExtractor that decodes the formatted
+// store of a solution into a State and computes the custom statistics from it.
+// This lets every model define statistics for its own output format.
+func StoreStatistics[Solution, State any](
+	f func(State) any,
+) StatisticsExtractor[Solution] {
+	return StatisticsFunc[Solution](func(solution Solution) (any, error) {
+		s := struct {
+			Store *State `json:"store"`
+		}{}
+		b, err := json.Marshal(solution)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+		if s.Store == nil {
+			return nil, nil
+		}
+		return f(*s.Store), nil
+	})
+}
+
+// EncoderOption configures the output of GenericEncoder.
+type EncoderOption func(*encoderOptions)
+
+// encoderOptions holds the options GenericEncoder is created with.
+type encoderOptions struct {
+	series *bool
+}
+
+// Series adds a series describing every solution to the statistics, if set.
+// The value is read when encoding, so it may point to a flag that the runner
+// only parses after the encoder is created.
+func Series(series *bool) EncoderOption {
+	return func(o *encoderOptions) {
+		o.series = series
+	}
+}
+
+// EnvBool returns the value of a boolean environment variable, false if it is
+// not set or not a boolean. It lets flags of a template default to the
+// environment like the options of the runner, e.g. RUNNER_OUTPUT_SERIES for
+// -runner.output.series.
+func EnvBool(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && value
+}
+
+// GenericEncoder returns a new Encoder that encodes the solution using the
+// given encoder. The statistics extractor computes the custom statistics of a
+// solution, no statistics are reported if it is nil.
+func GenericEncoder[Solution, Options any](
+	encoder encode.Encoder,
+	statistics StatisticsExtractor[Solution],
+	options ...EncoderOption,
+) run.Encoder[Solution, Options] {
+	enc := genericEncoder[Solution, Options]{
+		encoder:    encoder,
+		statistics: statistics,
+	}
+	for _, option := range options {
+		option(&enc.options)
+	}
+	return &enc
+}
+
+type genericEncoder[Solution, Options any] struct {
+	encoder    encode.Encoder
+	statistics StatisticsExtractor[Solution]
+	options    encoderOptions
+	// plan is the encoder of the routing plan chosen by the output path, nil
+	// if the solutions are written.
+	plan encode.Encoder
+}
+
+// Encode encodes the solution using the given encoder. If a given output path
+// ends in .gz or .zst, it will be compressed with gzip or zstd after encoding.
+// If it ends in .ndjson or .jsonl (optionally followed by .gz or .zst),
+// solutions are streamed: each one is written as a single line as soon as it
+// arrives. If it ends in .csv or .geojson, the routing plan of the best
+// solution is written in that format. The writer needs to be an io.Writer.
+// Statistics are reported for the last solution, which is the best one found.
+// With the Series option, a series describing every solution is added to
+// them.
+func (g *genericEncoder[Solution, Options]) Encode( //nolint:gocyclo
+	_ context.Context,
+	solutions <-chan Solution,
+	writer any,
+	runnerCfg any,
+	options Options,
+) (err error) {
+	closer, ok := writer.(io.Closer)
+	if ok {
+		defer func() {
+			tempErr := closer.Close()
+			// the first error is the most important
+			if err == nil {
+				err = tempErr
+			}
+		}()
+	}
+
+	ioWriter, ok := writer.(io.Writer)
+	if !ok {
+		err = errors.New("encoder is not compatible with configured IOProducer")
+		return err
+	}
+
+	stream := false
+	var plan encode.Encoder
+	if outputPather, ok := runnerCfg.(run.OutputPather); ok {
+		compressor, path, retErr := compressWriter(
+			ioWriter,
+			outputPather.OutputPath(),
+		)
+		if retErr != nil {
+			return retErr
+		}
+		if compressor != nil {
+			ioWriter = compressor
+			// The compressor is closed before the writer, which writes the
+			// trailer of the compressed data.
+			defer func() {
+				tempErr := compressor.Close()
+				if err == nil {
+					err = tempErr
+				}
+			}()
+		}
+		stream = strings.HasSuffix(path, ".ndjson") ||
+			strings.HasSuffix(path, ".jsonl")
+		plan = planEncoder(path)
+		g.plan = plan
+	}
+
+	// Metadata is only added if the runner configuration asks for it.
+	verbose := false
+	if quieter, ok := runnerCfg.(run.Quieter); ok {
+		verbose = !quieter.Quiet()
+	}
+
+	// Streamed solutions carry their own statistics, so no series is needed.
+	var series []seriesPoint
+	collectSeries := verbose && !stream && plan == nil &&
+		g.options.series != nil && *g.options.series
+
+	if limiter, ok := runnerCfg.(run.SolutionLimiter); ok {
+		solutionFlag, retErr := limiter.Solutions()
+		if retErr != nil {
+			return retErr
+		}
+
+		if solutionFlag == run.Last {
+			var last Solution
+			for solution := range solutions {
+				if collectSeries {
+					if series, err = g.appendSeries(series, solution); err != nil {
+						return err
+					}
+				}
+				last = solution
+			}
+			tempSolutions := make(chan Solution, 1)
+			tempSolutions <- last
+			close(tempSolutions)
+			solutions = tempSolutions
+			// The series already holds every solution.
+			collectSeries = false
+		}
+	}
+
+	if stream {
+		return g.stream(ioWriter, solutions, verbose, options)
+	}
+
+	// A routing plan is written for the best solution only.
+	if plan != nil {
+		var last Solution
+		for solution := range solutions {
+			last = solution
+		}
+		return plan.Encode(ioWriter, last)
+	}
+
+	if verbose {
+		m := meta[Options, Solution]{}
+		m.Version = version{
+			Sdk: sdk.VERSION,
+		}
+		m.Options = options
+		for solution := range solutions {
+			if collectSeries {
+				if series, err = g.appendSeries(series, solution); err != nil {
+					return err
+				}
+			}
+			m.Solutions = append(m.Solutions, solution)
+		}
+		// Solutions are only reported when they improve, so the last one is
+		// the best.
+		if len(m.Solutions) > 0 {
+			statistics, err := g.statisticsOf(m.Solutions[len(m.Solutions)-1])
+			if err != nil {
+				return err
+			}
+			if statistics != nil {
+				statistics.Series = series
+				m.Statistics = *statistics
+			}
+		}
+		if err = g.encoder.Encode(ioWriter, m); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	m := []Solution{}
+	for solution := range solutions {
+		m = append(m, solution)
+	}
+	if err = g.encoder.Encode(ioWriter, m); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// stream encodes every solution on its own as soon as it arrives. If verbose,
+// each solution is wrapped with the version, the options and its statistics.
+func (g *genericEncoder[Solution, Options]) stream(
+	ioWriter io.Writer,
+	solutions <-chan Solution,
+	verbose bool,
+	options Options,
+) error {
+	flusher, _ := ioWriter.(interface{ Flush() error })
+	for solution := range solutions {
+		var line any = solution
+		if verbose {
+			statistics, err := g.statisticsOf(solution)
+			if err != nil {
+				return err
+			}
+			line = streamedMeta[Options, Solution]{
+				Version:    version{Sdk: sdk.VERSION},
+				Options:    options,
+				Solution:   solution,
+				Statistics: statistics,
+			}
+		}
+		if err := g.encoder.Encode(ioWriter, line); err != nil {
+			return err
+		}
+		// Compressed lines are only visible once they are flushed.
+		if flusher != nil {
+			if err := flusher.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// statisticsOf computes the statistics of a solution. The value and elapsed
+// time are taken from the search statistics of the solution, the custom
+// statistics from the extractor. Nil is returned if there is nothing to report.
+func (g *genericEncoder[Solution, Options]) statisticsOf(
+	solution Solution,
+) (*statisticsOut, error) {
+	if g.statistics == nil {
+		return nil, nil
+	}
+	custom, err := g.statistics.Statistics(solution)
+	if err != nil || custom == nil {
+		return nil, err
+	}
+
+	s := output{}
+	b, err := json.Marshal(solution)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	value := 0.0
+	if s.Statistics.Value != nil {
+		value = float64(*s.Statistics.Value)
+	}
+
+	return &statisticsOut{
+		Schema: "v1",
+		Result: result{
+			Value:   value,
+			Elapsed: s.Statistics.Time.Elapsed.Seconds(),
+			Custom:  custom,
+		},
+	}, nil
+}
+
+// appendSeries appends the point describing a solution to the series. Solutions
+// without statistics are skipped.
+func (g *genericEncoder[Solution, Options]) appendSeries(
+	series []seriesPoint,
+	solution Solution,
+) ([]seriesPoint, error) {
+	statistics, err := g.statisticsOf(solution)
+	if err != nil || statistics == nil {
+		return series, err
+	}
+
+	point := seriesPoint{
+		Value:   statistics.Result.Value,
+		Elapsed: statistics.Result.Elapsed,
+	}
+	if s, ok := statistics.Result.Custom.(SeriesStatistics); ok {
+		point.Assigned = s.AssignedStops()
+		point.VehiclesUsed = s.VehiclesUsed()
+	}
+	return append(series, point), nil
+}
+
+// ContentType returns the content type of the output. If a routing plan is
+// written, as chosen by the output path when encoding, it is the content type
+// of the plan encoder.
+func (g *genericEncoder[Solution, Options]) ContentType() string {
+	encoder := g.encoder
+	if g.plan != nil {
+		encoder = g.plan
+	}
+	contentTyper, ok := encoder.(run.ContentTyper)
+	if !ok {
+		return "text/plain"
+	}
+	return contentTyper.ContentType()
+}
@@ -0,0 +1,162 @@
+package routing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// outputFormat returns the custom format of the plan.
+func outputFormat(d planData) func(p *route.Plan) any {
+	return func(p *route.Plan) any {
+		output, err := formatPlan(d, p)
+		if err != nil {
+			// Report the plan as it is together with the error, rather than
+			// stopping the search.
+			return map[string]any{
+				"error":      err.Error(),
+				"unassigned": p.Unassigned,
+				"vehicles":   p.Vehicles,
+			}
+		}
+		return output
+	}
+}
+
+// formatPlan formats a plan with the KPIs of its routes. An error is returned
+// if the plan holds a stop that is not part of the input.
+func formatPlan(d planData, p *route.Plan) (map[string]any, error) {
+	output := make(map[string]any)
+	vehicles := make([]any, len(p.Vehicles))
+	var totalEarliness, totalLateness, totalDuration, lifoViolations int
+	var totalCosts routeCosts
+	// Stops of every route by vehicle index, to explain unassigned stops with.
+	routes := make([][]int, len(d.vehicleMap))
+	for v, vehicle := range p.Vehicles {
+		route := make([]any, len(vehicle.Route))
+		// Stops of the route to check the loading order with.
+		var stops []int
+		// Locations and times of the route to compute its costs with.
+		locations := make([]int, len(vehicle.Route))
+		etas := make([]int, len(vehicle.Route))
+		etds := make([]int, len(vehicle.Route))
+		for i, stop := range vehicle.Route {
+			etas[i] = unix(stop.EstimatedArrival, stop.EstimatedDeparture)
+			etds[i] = unix(stop.EstimatedDeparture, stop.EstimatedArrival)
+			start := len(d.stops) + 2*d.vehicleMap[vehicle.ID]
+			if i == 0 {
+				locations[i] = start
+			} else {
+				locations[i] = start + 1
+			}
+
+			var target *TargetWindow
+			earliness := 0
+			lateness := 0
+
+			// The vehicle's start and end location are not important.
+			if i != 0 && i != len(vehicle.Route)-1 {
+				// Get the index of the stop.
+				stopIndex, ok := d.stopIndices[stop.ID]
+				if !ok {
+					return nil, fmt.Errorf("stop %q not found", stop.ID)
+				}
+				locations[i] = stopIndex
+				stops = append(stops, stopIndex)
+
+				target = d.routeData.targets[stopIndex]
+				if target != nil {
+					earliness, lateness = target.penalties(etas[i])
+				}
+			}
+
+			totalEarliness += earliness
+			totalLateness += lateness
+			route[i] = map[string]any{
+				"id":                  stop.ID,
+				"position":            stop.Position,
+				"estimated_arrival":   stop.EstimatedArrival,
+				"estimated_departure": stop.EstimatedDeparture,
+				"estimated_service":   stop.EstimatedService,
+				"target":              target,
+				"earliness":           earliness,
+				"lateness":            lateness,
+			}
+		}
+
+		plannedVehicle := map[string]any{
+			"id":             vehicle.ID,
+			"route":          route,
+			"route_duration": vehicle.RouteDuration,
+			"route_distance": vehicle.RouteDistance,
+		}
+		// The value of every term is only known for a custom objective.
+		if d.objective != nil {
+			costs := d.routeData.costs(locations, etas, etds)
+			totalCosts = totalCosts.add(costs)
+			plannedVehicle["objective"] = d.objective.contributions(costs)
+		}
+		vehicles[v] = plannedVehicle
+		routes[d.vehicleMap[vehicle.ID]] = stops
+		totalDuration += vehicle.RouteDuration
+		lifoViolations += d.loading.violations(stops)
+	}
+
+	unassigned, err := explainUnassigned(d, p.Unassigned, routes)
+	if err != nil {
+		return nil, err
+	}
+	output["unassigned"] = unassigned
+	output["vehicles"] = vehicles
+	output["lateness"] = totalLateness
+	output["earliness"] = totalEarliness
+	output["total_duration"] = totalDuration
+	output["num_lifo_violations"] = lifoViolations
+	if d.objective != nil {
+		output["objective"] = d.objective.contributions(totalCosts)
+	}
+
+	return output, nil
+}
+
+// explainUnassigned returns the unassigned stops of a plan together with the
+// reason why they are unassigned, given the stops of every route.
+func explainUnassigned(
+	d planData,
+	stops []route.Stop,
+	routes [][]int,
+) ([]any, error) {
+	indices := make([]int, len(stops))
+	for u, stop := range stops {
+		s, ok := d.stopIndices[stop.ID]
+		if !ok {
+			return nil, fmt.Errorf("stop %q not found", stop.ID)
+		}
+		indices[u] = s
+	}
+
+	reasons, explanations := d.unassigned.explain(indices, routes)
+	unassigned := make([]any, len(stops))
+	for u, stop := range stops {
+		unassigned[u] = map[string]any{
+			"id":          stop.ID,
+			"position":    stop.Position,
+			"reason":      reasons[u],
+			"explanation": explanations[u],
+		}
+	}
+	return unassigned, nil
+}
+
+// unix returns a planned time as unix time. If it is missing, as for the
+// arrival at the start of a route, the fallback is used.
+func unix(t, fallback *time.Time) int {
+	if t == nil {
+		t = fallback
+	}
+	if t == nil {
+		return 0
+	}
+	return int(t.Unix())
+}
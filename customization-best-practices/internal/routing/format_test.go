@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// generatedPlan returns an input with the given number of stops and vehicles
+// and a plan that spreads the stops evenly over the vehicles, one minute
+// apart.
+func generatedPlan(stops, vehicles int) (Input, *route.Plan) {
+	start := time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC)
+	i := Input{
+		Stops:              make([]route.Stop, stops),
+		Vehicles:           make([]string, vehicles),
+		TargetTimes:        make([]time.Time, stops),
+		EarlinessPenalties: make([]int, stops),
+		LatenessPenalties:  make([]int, stops),
+	}
+	for s := range i.Stops {
+		i.Stops[s] = route.Stop{
+			ID:       fmt.Sprintf("stop-%d", s),
+			Position: route.Position{Lon: 7 + float64(s%100)/100, Lat: 51},
+		}
+		i.TargetTimes[s] = start.Add(time.Duration(s/vehicles) * time.Minute)
+		i.EarlinessPenalties[s] = 1
+		i.LatenessPenalties[s] = 2
+	}
+
+	plan := &route.Plan{Vehicles: make([]route.PlannedVehicle, vehicles)}
+	for v := range i.Vehicles {
+		i.Vehicles[v] = fmt.Sprintf("vehicle-%d", v)
+		plan.Vehicles[v].ID = i.Vehicles[v]
+	}
+	plannedStop := func(id string, minutes int) route.PlannedStop {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return route.PlannedStop{
+			Stop:               route.Stop{ID: id},
+			EstimatedArrival:   &t,
+			EstimatedDeparture: &t,
+			EstimatedService:   &t,
+		}
+	}
+	for v := range plan.Vehicles {
+		vehicle := &plan.Vehicles[v]
+		vehicle.Route = append(vehicle.Route, plannedStop(vehicle.ID+"-start", 0))
+		for s := v; s < stops; s += vehicles {
+			stop := plannedStop(i.Stops[s].ID, s/vehicles+1)
+			stop.Position = i.Stops[s].Position
+			vehicle.Route = append(vehicle.Route, stop)
+		}
+		vehicle.Route = append(
+			vehicle.Route,
+			plannedStop(vehicle.ID+"-end", stops/vehicles+2),
+		)
+	}
+	return i, plan
+}
+
+func TestFormatPlanUnknownStop(t *testing.T) {
+	i, plan := generatedPlan(10, 2)
+	d, err := newPlanData(i, features{value: true, loading: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Vehicles[0].Route[1].ID = "unknown"
+
+	if _, err := formatPlan(d, plan); err == nil {
+		t.Error("expected an error for an unknown stop")
+	}
+	output, ok := outputFormat(d)(plan).(map[string]any)
+	if !ok || output["error"] == nil {
+		t.Errorf("expected the output to report the error, got %v", output)
+	}
+}
+
+func BenchmarkOutputFormat(b *testing.B) {
+	for _, stops := range []int{200, 2000, 10000} {
+		b.Run(fmt.Sprintf("stops=%d", stops), func(b *testing.B) {
+			i, plan := generatedPlan(stops, 20)
+			d, err := newPlanData(i, features{value: true, loading: true})
+			if err != nil {
+				b.Fatal(err)
+			}
+			format := outputFormat(d)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				format(plan)
+			}
+		})
+	}
+}
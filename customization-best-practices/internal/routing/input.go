@@ -0,0 +1,43 @@
+// Package routing holds the code shared by the routing templates of the
+// customization best practices. The templates differ only in the features
+// they pick when creating a solver.
+package routing
+
+import (
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// Input describes the expected json input by the runner. Fields of features a
+// template does not use are ignored, such as the objective without
+// CustomValue and the labels without LoadingConstraint. In case you would
+// like to support a different input format you can change the struct as you
+// see fit. You may need to change some code in NewSolver to use the new
+// structure.
+type Input struct {
+	Stops               []route.Stop       `json:"stops"`
+	Vehicles            []string           `json:"vehicles"`
+	Starts              []route.Position   `json:"starts"`
+	Ends                []route.Position   `json:"ends"`
+	Quantities          []int              `json:"quantities"`
+	Capacities          []int              `json:"capacities"`
+	Precedences         []route.Job        `json:"precedences"`
+	Velocities          []float64          `json:"velocities"`
+	ServiceTimes        []route.Service    `json:"service_times"`
+	Shifts              []route.TimeWindow `json:"shifts"`
+	EarlinessPenalties  []int              `json:"earliness_penalties"`
+	LatenessPenalties   []int              `json:"lateness_penalties"`
+	TargetTimes         []time.Time        `json:"target_times"`
+	TargetWindows       []*TargetWindow    `json:"target_windows"`
+	UnassignedPenalties []int              `json:"unassigned_penalties"`
+	Labels              []Label            `json:"labels"`
+	Objective           []ObjectiveTerm    `json:"objective"`
+	SpeedProfiles       []SpeedProfile     `json:"speed_profiles"`
+}
+
+// Label assigns a loading mode to a pickup.
+type Label struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
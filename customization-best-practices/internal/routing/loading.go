@@ -0,0 +1,118 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Loading modes a pickup can be labeled with. Items of a LIFO pickup are
+// loaded like onto a stack and must be the last item loaded of all items on
+// board when they are dropped off. Items of a FIFO pickup are loaded like into
+// a queue and must be the first item loaded of all items on board.
+const (
+	lifoLoading = "lifo"
+	fifoLoading = "fifo"
+)
+
+// loadingRules holds the loading mode of labeled pickups and the pickup of
+// their dropoffs, both by stop index.
+type loadingRules struct {
+	modes   map[int]string
+	pickups map[int]int
+}
+
+// newLoadingRules returns the loading rules of the input. Every label must
+// name the pickup of a precedence and a known loading mode.
+func newLoadingRules(i Input) (loadingRules, error) {
+	stops := make(map[string]int, len(i.Stops))
+	for s, stop := range i.Stops {
+		stops[stop.ID] = s
+	}
+
+	dropoffs := make(map[int]int, len(i.Precedences))
+	for _, p := range i.Precedences {
+		pickup, ok := stops[p.PickUp]
+		if !ok {
+			return loadingRules{}, fmt.Errorf(
+				"precedence: unknown pickup %q", p.PickUp,
+			)
+		}
+		dropoff, ok := stops[p.DropOff]
+		if !ok {
+			return loadingRules{}, fmt.Errorf(
+				"precedence: unknown dropoff %q", p.DropOff,
+			)
+		}
+		dropoffs[pickup] = dropoff
+	}
+
+	rules := loadingRules{
+		modes:   make(map[int]string, len(i.Labels)),
+		pickups: make(map[int]int, len(i.Labels)),
+	}
+	for _, l := range i.Labels {
+		mode := strings.ToLower(l.Label)
+		if mode != lifoLoading && mode != fifoLoading {
+			return loadingRules{}, fmt.Errorf(
+				"label of stop %q: unknown loading mode %q", l.ID, l.Label,
+			)
+		}
+		pickup, ok := stops[l.ID]
+		if !ok {
+			return loadingRules{}, fmt.Errorf("label: unknown stop %q", l.ID)
+		}
+		dropoff, ok := dropoffs[pickup]
+		if !ok {
+			return loadingRules{}, fmt.Errorf(
+				"label of stop %q: stop is not a pickup", l.ID,
+			)
+		}
+		rules.modes[pickup] = mode
+		rules.pickups[dropoff] = pickup
+	}
+	return rules, nil
+}
+
+// violations returns the number of dropoffs on a route that break the loading
+// mode of their pickup. The route holds stop indices without the start and end
+// of the vehicle. Only items of labeled pickups are tracked, and items that are
+// not dropped off yet stay on board.
+func (r loadingRules) violations(locations []int) int {
+	if len(r.modes) == 0 {
+		return 0
+	}
+
+	violations := 0
+	// Items on board, by the stop index of their pickup, in loading order.
+	var onBoard []int
+	for _, location := range locations {
+		// Items are dropped off before new ones are loaded at a stop.
+		if pickup, ok := r.pickups[location]; ok {
+			position := r.position(onBoard, pickup)
+			// A pickup missing before its dropoff is up to the precedence to
+			// rule out.
+			if position != -1 {
+				if r.modes[pickup] == lifoLoading && position != len(onBoard)-1 ||
+					r.modes[pickup] == fifoLoading && position != 0 {
+					violations++
+				}
+				onBoard = append(onBoard[:position], onBoard[position+1:]...)
+			}
+		}
+		if _, ok := r.modes[location]; ok {
+			onBoard = append(onBoard, location)
+		}
+	}
+	return violations
+}
+
+// position returns the position of the item of a pickup on board, -1 if it is
+// not on board.
+func (r loadingRules) position(onBoard []int, pickup int) int {
+	for p, item := range onBoard {
+		if item == pickup {
+			return p
+		}
+	}
+	return -1
+}
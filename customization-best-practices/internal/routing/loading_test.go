@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+func TestLoadingRulesViolations(t *testing.T) {
+	// Two pickups p1 and p2 with dropoffs d1 and d2 and an unlabeled stop x.
+	stops := []route.Stop{
+		{ID: "p1"}, {ID: "d1"}, {ID: "p2"}, {ID: "d2"}, {ID: "x"},
+	}
+	p1, d1, p2, d2, x := 0, 1, 2, 3, 4
+	precedences := []route.Job{
+		{PickUp: "p1", DropOff: "d1"},
+		{PickUp: "p2", DropOff: "d2"},
+	}
+
+	tests := []struct {
+		name      string
+		labels    []Label
+		locations []int
+		want      int
+	}{
+		{
+			name:      "lifo nested",
+			labels:    []Label{{"p1", "lifo"}, {"p2", "lifo"}},
+			locations: []int{p1, p2, x, d2, d1},
+		},
+		{
+			name:      "lifo crossed",
+			labels:    []Label{{"p1", "lifo"}, {"p2", "lifo"}},
+			locations: []int{p1, p2, d1, d2},
+			want:      1,
+		},
+		{
+			name:      "fifo crossed",
+			labels:    []Label{{"p1", "FIFO"}, {"p2", "fifo"}},
+			locations: []int{p1, p2, d1, d2},
+		},
+		{
+			name:      "fifo nested",
+			labels:    []Label{{"p1", "fifo"}, {"p2", "fifo"}},
+			locations: []int{p1, p2, d2, d1},
+			want:      1,
+		},
+		{
+			name:      "unlabeled items are not tracked",
+			labels:    []Label{{"p1", "lifo"}},
+			locations: []int{p1, p2, d1, d2},
+		},
+		{
+			name:      "partial route",
+			labels:    []Label{{"p1", "lifo"}, {"p2", "lifo"}},
+			locations: []int{p1, p2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := newLoadingRules(Input{
+				Stops:       stops,
+				Precedences: precedences,
+				Labels:      test.labels,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rules.violations(test.locations); got != test.want {
+				t.Errorf("got %d violations, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewLoadingRulesErrors(t *testing.T) {
+	stops := []route.Stop{{ID: "p1"}, {ID: "d1"}}
+	precedences := []route.Job{{PickUp: "p1", DropOff: "d1"}}
+
+	for name, labels := range map[string][]Label{
+		"unknown mode": {{"p1", "stack"}},
+		"unknown stop": {{"p2", "lifo"}},
+		"not a pickup": {{"d1", "lifo"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newLoadingRules(Input{
+				Stops:       stops,
+				Precedences: precedences,
+				Labels:      labels,
+			})
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
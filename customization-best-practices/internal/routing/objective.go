@@ -0,0 +1,189 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// Names of the terms the objective can be composed of.
+const (
+	routeDurationTerm = "route_duration"
+	earlinessTerm     = "earliness"
+	latenessTerm      = "lateness"
+	distanceTerm      = "distance"
+	vehiclesUsedTerm  = "vehicles_used"
+)
+
+// ObjectiveTerm switches a term of the objective on and weights it. Route
+// duration is measured in seconds, distance in meters and earliness and
+// lateness in seconds times the penalty of the stop.
+type ObjectiveTerm struct {
+	Term   string  `json:"term"`
+	Weight float64 `json:"weight"`
+}
+
+// defaultObjective is used if the input does not define an objective.
+var defaultObjective = []ObjectiveTerm{
+	{Term: routeDurationTerm, Weight: 1},
+	{Term: earlinessTerm, Weight: 1},
+	{Term: latenessTerm, Weight: 1},
+}
+
+// objective is the weighted sum of its terms.
+type objective []ObjectiveTerm
+
+// newObjective validates the given terms and returns the objective composed
+// of them.
+func newObjective(terms []ObjectiveTerm) (objective, error) {
+	if len(terms) == 0 {
+		return defaultObjective, nil
+	}
+
+	seen := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		switch t.Term {
+		case routeDurationTerm, earlinessTerm, latenessTerm, distanceTerm,
+			vehiclesUsedTerm:
+		default:
+			return nil, fmt.Errorf("objective: unknown term %q", t.Term)
+		}
+		if seen[t.Term] {
+			return nil, fmt.Errorf("objective: term %q given twice", t.Term)
+		}
+		if t.Weight < 0 {
+			return nil, fmt.Errorf(
+				"objective: term %q has negative weight %v", t.Term, t.Weight,
+			)
+		}
+		seen[t.Term] = true
+	}
+	return terms, nil
+}
+
+// routeCosts holds the unweighted value of every term for a single route.
+type routeCosts struct {
+	duration     int
+	earliness    int
+	lateness     int
+	distance     float64
+	vehiclesUsed int
+}
+
+// add returns the sum of both costs.
+func (c routeCosts) add(other routeCosts) routeCosts {
+	return routeCosts{
+		duration:     c.duration + other.duration,
+		earliness:    c.earliness + other.earliness,
+		lateness:     c.lateness + other.lateness,
+		distance:     c.distance + other.distance,
+		vehiclesUsed: c.vehiclesUsed + other.vehiclesUsed,
+	}
+}
+
+// of returns the unweighted value of a term.
+func (c routeCosts) of(term string) float64 {
+	switch term {
+	case routeDurationTerm:
+		return float64(c.duration)
+	case earlinessTerm:
+		return float64(c.earliness)
+	case latenessTerm:
+		return float64(c.lateness)
+	case distanceTerm:
+		return c.distance
+	case vehiclesUsedTerm:
+		return float64(c.vehiclesUsed)
+	}
+	return 0
+}
+
+// value returns the weighted sum of the costs.
+func (o objective) value(c routeCosts) int {
+	value := 0.0
+	for _, t := range o {
+		value += t.Weight * c.of(t.Term)
+	}
+	return int(math.Round(value))
+}
+
+// contributions returns the weighted value of every term of the objective.
+func (o objective) contributions(c routeCosts) map[string]float64 {
+	contributions := make(map[string]float64, len(o))
+	for _, t := range o {
+		contributions[t.Term] = t.Weight * c.of(t.Term)
+	}
+	return contributions
+}
+
+// routeData holds the data needed to compute the costs of a route. Locations
+// are indexed like the router does: first the stops, followed by the start and
+// end of every vehicle.
+type routeData struct {
+	targets   []*TargetWindow
+	positions []route.Position
+}
+
+// newRouteData returns the route data for the input and the target windows of
+// its stops.
+func newRouteData(i Input, targets []*TargetWindow) routeData {
+	positions := make([]route.Position, 0, len(i.Stops)+2*len(i.Vehicles))
+	for _, s := range i.Stops {
+		positions = append(positions, s.Position)
+	}
+	for v := range i.Vehicles {
+		var start, end route.Position
+		if v < len(i.Starts) {
+			start = i.Starts[v]
+		}
+		if v < len(i.Ends) {
+			end = i.Ends[v]
+		}
+		positions = append(positions, start, end)
+	}
+
+	return routeData{
+		targets:   targets,
+		positions: positions,
+	}
+}
+
+// costs computes the costs of a route visiting the given locations with the
+// given arrival and departure times as unix times. An unused vehicle has no
+// costs.
+func (d routeData) costs(locations []int, etas, etds []int) routeCosts {
+	if len(locations) <= 2 {
+		return routeCosts{}
+	}
+
+	c := routeCosts{
+		duration:     etds[len(etds)-1] - etas[0],
+		vehiclesUsed: 1,
+	}
+	haversine := route.HaversineByPoint()
+	for i, l := range locations {
+		// Arriving outside the target window of a stop is penalized. The
+		// output format uses the same penalties, so reported and optimized
+		// values agree.
+		if l < len(d.targets) && d.targets[l] != nil {
+			earliness, lateness := d.targets[l].penalties(etas[i])
+			c.earliness += earliness
+			c.lateness += lateness
+		}
+
+		// Legs from or to a location without a position are not driven.
+		if i == 0 || l >= len(d.positions) || locations[i-1] >= len(d.positions) {
+			continue
+		}
+		from, to := d.positions[locations[i-1]], d.positions[l]
+		if from == (route.Position{}) || to == (route.Position{}) {
+			continue
+		}
+		c.distance += haversine.Cost(
+			route.Point{from.Lon, from.Lat},
+			route.Point{to.Lon, to.Lat},
+		)
+	}
+	return c
+}
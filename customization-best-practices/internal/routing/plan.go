@@ -0,0 +1,219 @@
+package routing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+	"github.com/nextmv-io/sdk/run/encode"
+)
+
+// routePlan is the routing plan of a solution, as formatted by outputFormat.
+type routePlan struct {
+	Unassigned []route.Stop   `json:"unassigned"`
+	Vehicles   []plannedRoute `json:"vehicles"`
+}
+
+type plannedRoute struct {
+	ID    string        `json:"id"`
+	Route []plannedStop `json:"route"`
+}
+
+type plannedStop struct {
+	ID                 string         `json:"id"`
+	Position           route.Position `json:"position"`
+	EstimatedArrival   *time.Time     `json:"estimated_arrival"`
+	EstimatedDeparture *time.Time     `json:"estimated_departure"`
+	Earliness          int            `json:"earliness"`
+	Lateness           int            `json:"lateness"`
+}
+
+// planOf returns the routing plan held in the store of a solution.
+func planOf(solution any) (routePlan, error) {
+	s := struct {
+		Store routePlan `json:"store"`
+	}{}
+	b, err := json.Marshal(solution)
+	if err != nil {
+		return routePlan{}, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s.Store, err
+}
+
+// planEncoder returns the encoder for routing plans that belongs to the
+// extension of the path, nil if the path is not meant for a routing plan.
+func planEncoder(path string) encode.Encoder {
+	switch {
+	case strings.HasSuffix(path, ".csv"):
+		return CSV()
+	case strings.HasSuffix(path, ".geojson"):
+		return GeoJSON()
+	}
+	return nil
+}
+
+// CSV returns a new encoder that writes the routing plan of a solution as CSV.
+func CSV() encode.Encoder {
+	return CSVEncoder{}
+}
+
+// CSVEncoder is an Encoder that writes one row per stop on the route of each
+// vehicle, including the start and end of the route.
+type CSVEncoder struct{}
+
+// Encode writes the routing plan of the solution v as CSV to w.
+func (c CSVEncoder) Encode(w io.Writer, v any) error {
+	plan, err := planOf(v)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	err = writer.Write([]string{
+		"vehicle",
+		"sequence",
+		"stop",
+		"lon",
+		"lat",
+		"estimated_arrival",
+		"estimated_departure",
+		"earliness",
+		"lateness",
+	})
+	if err != nil {
+		return err
+	}
+	for _, vehicle := range plan.Vehicles {
+		for i, stop := range vehicle.Route {
+			err := writer.Write([]string{
+				vehicle.ID,
+				strconv.Itoa(i),
+				stop.ID,
+				strconv.FormatFloat(stop.Position.Lon, 'f', -1, 64),
+				strconv.FormatFloat(stop.Position.Lat, 'f', -1, 64),
+				formatTime(stop.EstimatedArrival),
+				formatTime(stop.EstimatedDeparture),
+				strconv.Itoa(stop.Earliness),
+				strconv.Itoa(stop.Lateness),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ContentType returns the content type of the encoder.
+func (c CSVEncoder) ContentType() string {
+	return "text/csv"
+}
+
+// formatTime formats a planned time as RFC 3339, empty if there is none.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// GeoJSON returns a new encoder that writes the routing plan of a solution as
+// a GeoJSON FeatureCollection.
+func GeoJSON() encode.Encoder {
+	return GeoJSONEncoder{}
+}
+
+// GeoJSONEncoder is an Encoder that writes a Point feature for every stop and
+// a LineString feature for the route of every vehicle. Unassigned stops are
+// marked as such in their properties.
+type GeoJSONEncoder struct{}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// Encode writes the routing plan of the solution v as GeoJSON to w.
+func (g GeoJSONEncoder) Encode(w io.Writer, v any) error {
+	plan, err := planOf(v)
+	if err != nil {
+		return err
+	}
+
+	collection := featureCollection{
+		Type:     "FeatureCollection",
+		Features: []feature{},
+	}
+	for _, vehicle := range plan.Vehicles {
+		line := make([][2]float64, len(vehicle.Route))
+		for i, stop := range vehicle.Route {
+			line[i] = coordinates(stop.Position)
+			// The vehicle's start and end location are part of the route
+			// only.
+			if i == 0 || i == len(vehicle.Route)-1 {
+				continue
+			}
+			collection.Features = append(collection.Features, feature{
+				Type:     "Feature",
+				Geometry: geometry{Type: "Point", Coordinates: line[i]},
+				Properties: map[string]any{
+					"id":                  stop.ID,
+					"vehicle":             vehicle.ID,
+					"sequence":            i,
+					"estimated_arrival":   stop.EstimatedArrival,
+					"estimated_departure": stop.EstimatedDeparture,
+					"earliness":           stop.Earliness,
+					"lateness":            stop.Lateness,
+				},
+			})
+		}
+		// A LineString needs at least two positions.
+		if len(line) < 2 {
+			continue
+		}
+		collection.Features = append(collection.Features, feature{
+			Type:       "Feature",
+			Geometry:   geometry{Type: "LineString", Coordinates: line},
+			Properties: map[string]any{"vehicle": vehicle.ID},
+		})
+	}
+	for _, stop := range plan.Unassigned {
+		collection.Features = append(collection.Features, feature{
+			Type: "Feature",
+			Geometry: geometry{
+				Type:        "Point",
+				Coordinates: coordinates(stop.Position),
+			},
+			Properties: map[string]any{"id": stop.ID, "unassigned": true},
+		})
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
+
+// ContentType returns the content type of the encoder.
+func (g GeoJSONEncoder) ContentType() string {
+	return "application/geo+json"
+}
+
+// coordinates returns a position in GeoJSON order, longitude first.
+func coordinates(p route.Position) [2]float64 {
+	return [2]float64{p.Lon, p.Lat}
+}
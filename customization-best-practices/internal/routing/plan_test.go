@@ -0,0 +1,150 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/encode"
+)
+
+// testPlan returns a solution holding a formatted plan with a single vehicle
+// that serves one stop, and one unassigned stop. The vehicle has no arrival
+// at its start and no departure from its end.
+func testPlan() map[string]any {
+	start := time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC)
+	arrival := start.Add(10 * time.Minute)
+	departure := arrival.Add(5 * time.Minute)
+	end := departure.Add(10 * time.Minute)
+	depot := route.Position{Lon: 7.0, Lat: 51.0}
+
+	return map[string]any{
+		"store": routePlan{
+			Unassigned: []route.Stop{
+				{ID: "b", Position: route.Position{Lon: 7.2, Lat: 51.2}},
+			},
+			Vehicles: []plannedRoute{{
+				ID: "v1",
+				Route: []plannedStop{
+					{ID: "v1-start", Position: depot, EstimatedDeparture: &start},
+					{
+						ID:                 "a",
+						Position:           route.Position{Lon: 7.1, Lat: 51.1},
+						EstimatedArrival:   &arrival,
+						EstimatedDeparture: &departure,
+						Lateness:           60,
+					},
+					{ID: "v1-end", Position: depot, EstimatedArrival: &end},
+				},
+			}},
+		},
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	var b bytes.Buffer
+	if err := CSV().Encode(&b, testPlan()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "vehicle,sequence,stop,lon,lat,estimated_arrival," +
+		"estimated_departure,earliness,lateness\n" +
+		"v1,0,v1-start,7,51,,2023-01-01T08:00:00Z,0,0\n" +
+		"v1,1,a,7.1,51.1,2023-01-01T08:10:00Z,2023-01-01T08:15:00Z,0,60\n" +
+		"v1,2,v1-end,7,51,2023-01-01T08:25:00Z,,0,0\n"
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGeoJSONEncoder(t *testing.T) {
+	var b bytes.Buffer
+	if err := GeoJSON().Encode(&b, testPlan()); err != nil {
+		t.Fatal(err)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Type != "FeatureCollection" {
+		t.Errorf("type = %q, want FeatureCollection", collection.Type)
+	}
+
+	type summary struct {
+		geometry    string
+		coordinates string
+		id          any
+		unassigned  any
+	}
+	var got []summary
+	for _, f := range collection.Features {
+		got = append(got, summary{
+			geometry:    f.Geometry.Type,
+			coordinates: string(f.Geometry.Coordinates),
+			id:          f.Properties["id"],
+			unassigned:  f.Properties["unassigned"],
+		})
+	}
+	// The start and end of the route are only part of the LineString.
+	want := []summary{
+		{geometry: "Point", coordinates: "[7.1,51.1]", id: "a"},
+		{geometry: "LineString", coordinates: "[[7,51],[7.1,51.1],[7,51]]"},
+		{
+			geometry:    "Point",
+			coordinates: "[7.2,51.2]",
+			id:          "b",
+			unassigned:  true,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGenericEncoderPlanContentType(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want string
+	}{
+		{"plan.csv", "text/csv"},
+		{"plan.geojson", "application/geo+json"},
+		{"output.json", "application/json"},
+	} {
+		encoder := GenericEncoder[map[string]any, struct{}](encode.JSON(), nil)
+		var cfg run.CLIRunnerConfig
+		cfg.Runner.Output.Path = test.path
+		cfg.Runner.Output.Solutions = "last"
+
+		solutions := make(chan map[string]any, 1)
+		solutions <- testPlan()
+		close(solutions)
+		var b bytes.Buffer
+		err := encoder.Encode(context.Background(), solutions, &b, cfg, struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contentTyper, ok := encoder.(run.ContentTyper)
+		if !ok {
+			t.Fatal("encoder has no content type")
+		}
+		if got := contentTyper.ContentType(); got != test.want {
+			t.Errorf("%s: content type %q, want %q", test.path, got, test.want)
+		}
+	}
+}
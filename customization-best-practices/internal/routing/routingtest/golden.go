@@ -0,0 +1,238 @@
+// Package routingtest runs the routing templates on the shared inputs and
+// compares the key figures of their solutions against golden files.
+package routingtest
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/your_project/routing/internal/routing"
+	"github.com/nextmv-io/sdk"
+	"github.com/nextmv-io/sdk/store"
+)
+
+// update regenerates the golden files instead of comparing against them.
+var update = flag.Bool("update", false, "regenerate the golden files")
+
+// Duration is the time limit every input is solved with.
+const Duration = 2 * time.Second
+
+// KPIs are the key figures of a solution that are compared against the golden
+// files.
+type KPIs struct {
+	Value          float64 `json:"value"`
+	Unassigned     int     `json:"unassigned"`
+	Earliness      int     `json:"earliness"`
+	Lateness       int     `json:"lateness"`
+	LifoViolations int     `json:"lifo_violations"`
+}
+
+// tolerance is the deviation from a golden value that is accepted, as a
+// fraction of the golden value or in absolute terms, whichever is larger.
+type tolerance struct {
+	relative float64
+	absolute float64
+}
+
+// tolerances of the KPIs. The search is stopped by a time limit, so a slower
+// machine may find a slightly worse plan. Stops and loading violations must
+// match exactly.
+var tolerances = map[string]tolerance{
+	"value":           {relative: 0.05},
+	"unassigned":      {},
+	"earliness":       {relative: 0.1, absolute: 60},
+	"lateness":        {relative: 0.1, absolute: 60},
+	"lifo_violations": {},
+}
+
+// Solver is the signature of the solver of a template.
+type Solver func(routing.Input, store.Options) (store.Solver, error)
+
+// Golden solves every input in dir matching input*.json with the solver and
+// compares the KPIs of the best solution with the golden file of the same
+// name in testdata/golden. The golden files are regenerated with -update. The
+// test is skipped if the sdk plugin is not installed and fails if a golden file
+// is missing.
+func Golden(t *testing.T, solver Solver, dir string) {
+	t.Helper()
+	if !pluginInstalled() {
+		t.Skip("the nextmv sdk plugin is not installed")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "input*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no inputs found in %s", dir)
+	}
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		t.Run(strings.TrimSuffix(name, ".json"), func(t *testing.T) {
+			got, err := solve(solver, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "golden", name)
+			if *update {
+				if err := write(golden, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			b, err := os.ReadFile(golden)
+			if os.IsNotExist(err) {
+				t.Fatalf("no golden file %s, run the test with -update", golden)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want KPIs
+			if err := json.Unmarshal(b, &want); err != nil {
+				t.Fatal(err)
+			}
+			compare(t, got, want)
+		})
+	}
+}
+
+// solve runs the solver on the input at path and returns the KPIs of the best
+// solution.
+func solve(solver Solver, path string) (KPIs, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return KPIs{}, err
+	}
+	var i routing.Input
+	if err := json.Unmarshal(b, &i); err != nil {
+		return KPIs{}, err
+	}
+
+	opts := store.DefaultOptions()
+	opts.Limits.Duration = Duration
+	s, err := solver(i, opts)
+	if err != nil {
+		return KPIs{}, err
+	}
+
+	return kpis(s.Last(context.Background()))
+}
+
+// kpis extracts the KPIs from the custom format of a solution.
+func kpis(solution store.Solution) (KPIs, error) {
+	s := struct {
+		Store struct {
+			Earliness         int               `json:"earliness"`
+			Lateness          int               `json:"lateness"`
+			NumLifoViolations int               `json:"num_lifo_violations"`
+			Unassigned        []json.RawMessage `json:"unassigned"`
+		} `json:"store"`
+		Statistics struct {
+			Value *int `json:"value"`
+		} `json:"statistics"`
+	}{}
+	b, err := json.Marshal(solution)
+	if err != nil {
+		return KPIs{}, err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return KPIs{}, err
+	}
+	if s.Statistics.Value == nil {
+		return KPIs{}, fmt.Errorf("solution has no value")
+	}
+
+	return KPIs{
+		Value:          float64(*s.Statistics.Value),
+		Unassigned:     len(s.Store.Unassigned),
+		Earliness:      s.Store.Earliness,
+		Lateness:       s.Store.Lateness,
+		LifoViolations: s.Store.NumLifoViolations,
+	}, nil
+}
+
+// compare reports every KPI that deviates from the golden one by more than
+// its tolerance.
+func compare(t *testing.T, got, want KPIs) {
+	t.Helper()
+	for _, kpi := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"value", got.Value, want.Value},
+		{"unassigned", float64(got.Unassigned), float64(want.Unassigned)},
+		{"earliness", float64(got.Earliness), float64(want.Earliness)},
+		{"lateness", float64(got.Lateness), float64(want.Lateness)},
+		{
+			"lifo_violations",
+			float64(got.LifoViolations),
+			float64(want.LifoViolations),
+		},
+	} {
+		tol := tolerances[kpi.name]
+		allowed := math.Max(tol.relative*math.Abs(kpi.want), tol.absolute)
+		if math.Abs(kpi.got-kpi.want) > allowed {
+			t.Errorf(
+				"%s = %v, want %v (tolerance %v)",
+				kpi.name, kpi.got, kpi.want, allowed,
+			)
+		}
+	}
+}
+
+// write writes the KPIs to the golden file at path.
+func write(path string, k KPIs) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// pluginInstalled reports whether the sdk plugin can be found where the sdk
+// looks for it. The sdk exits the process if it cannot find the plugin, so
+// this needs to be checked before solving.
+func pluginInstalled() bool {
+	filename := fmt.Sprintf(
+		"nextmv-sdk-%s-%s-%s-%s.so",
+		sdk.VERSION,
+		runtime.Version(),
+		runtime.GOOS,
+		runtime.GOARCH,
+	)
+
+	var dirs []string
+	if library := os.Getenv("NEXTMV_LIBRARY_PATH"); library != "" {
+		dirs = append(dirs, library)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".nextmv", "lib"))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, cwd)
+	}
+	if executable, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(executable))
+	}
+
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, filename)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,332 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+	"github.com/nextmv-io/sdk/store"
+)
+
+// features holds the features a solver is created with.
+type features struct {
+	format       bool
+	value        bool
+	loading      bool
+	threads      int
+	validateOnly bool
+}
+
+// Option switches on a feature of the solver.
+type Option func(*features)
+
+// CustomFormat formats the plan with the KPIs of its routes: the earliness and
+// lateness of every stop, the route durations, the loading order violations
+// and the reason why stops are unassigned.
+func CustomFormat() Option {
+	return func(f *features) {
+		f.format = true
+	}
+}
+
+// CustomValue replaces the value function of the router with the objective
+// given in the input, which weighs route duration, earliness and lateness by
+// default. The custom format then also reports the value of every term.
+func CustomValue() Option {
+	return func(f *features) {
+		f.value = true
+	}
+}
+
+// LoadingConstraint rules out routes that break the loading order of the
+// labeled pickups.
+func LoadingConstraint() Option {
+	return func(f *features) {
+		f.loading = true
+	}
+}
+
+// Threads sets the number of threads the router searches with. With a single
+// thread only the decision diagram solver is used, so runs vary less. The
+// search is still stopped by its duration limit, so a slower machine may find
+// a worse plan.
+func Threads(threads int) Option {
+	return func(f *features) {
+		f.threads = threads
+	}
+}
+
+// ValidateOnly makes the solver validate the input and report its problems
+// instead of searching for a plan, if set.
+func ValidateOnly(validateOnly bool) Option {
+	return func(f *features) {
+		f.validateOnly = validateOnly
+	}
+}
+
+// DefaultTemplate returns the features of routing-default: the router keeps
+// its default value function and only the output is formatted.
+func DefaultTemplate() []Option {
+	return []Option{
+		CustomFormat(),
+	}
+}
+
+// ValueTemplate returns the features of routing-customized-value: the value
+// function is replaced with the weighted objective terms of the input.
+func ValueTemplate() []Option {
+	return []Option{
+		CustomFormat(),
+		CustomValue(),
+	}
+}
+
+// ConstraintTemplate returns the features of
+// routing-customized-value-constraint: the custom value function together
+// with the loading constraint.
+func ConstraintTemplate() []Option {
+	return []Option{
+		CustomFormat(),
+		CustomValue(),
+		LoadingConstraint(),
+	}
+}
+
+// SolverOptions returns the solver options with the settings the templates
+// share. You can also fix other solver options here.
+func SolverOptions(opts store.Options) store.Options {
+	opts.Diagram.Expansion.Limit = 1
+	// A duration limit of 0 is treated as infinity. For cloud runs you need to
+	// set an explicit duration limit which is why it is currently set to 10s
+	// here in case no duration limit is set. For local runs there is no time
+	// limitation. If you want to make cloud runs for longer than 5 minutes,
+	// please contact: support@nextmv.io
+	if opts.Limits.Duration == 0 {
+		opts.Limits.Duration = 10 * time.Second
+	}
+	return opts
+}
+
+// NewSolver takes the input and solver options and constructs a routing
+// solver with the given features. All route features/options depend on the
+// input format. Please see the [route package
+// documentation](https://pkg.go.dev/github.com/nextmv-io/sdk/route) for further
+// information on the options available to you.
+func NewSolver(
+	i Input,
+	opts store.Options,
+	options ...Option,
+) (store.Solver, error) {
+	var f features
+	for _, option := range options {
+		option(&f)
+	}
+
+	// In case you directly expose the solver to untrusted, external input,
+	// it is advisable from a security point of view to add strong
+	// input validations before passing the data to the solver.
+	err := validate(i)
+	if f.validateOnly {
+		return validationSolver(err, opts), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPlanData(i, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define base router.
+	routerOptions := []route.Option{
+		route.Starts(i.Starts),
+		route.Ends(i.Ends),
+		route.Shifts(i.Shifts),
+		route.Capacity(i.Quantities, i.Capacities),
+		route.Precedence(i.Precedences),
+		route.Services(i.ServiceTimes),
+	}
+	// With speed profiles, travel times depend on the time of day and replace
+	// the constant velocities.
+	if len(i.SpeedProfiles) > 0 {
+		measures := travelTimeMeasures(p.speeds, p.routeData.positions)
+		routerOptions = append(
+			routerOptions,
+			route.TravelTimeDependentMeasures(measures),
+		)
+	} else {
+		routerOptions = append(routerOptions, route.Velocities(i.Velocities))
+	}
+	if f.value {
+		v := vehicleData{
+			objective: p.objective,
+			routeData: p.routeData,
+		}
+		routerOptions = append(routerOptions, route.Update(v, p))
+	}
+	if f.loading {
+		constraint := CustomConstraint{
+			loading: p.loading,
+		}
+		routerOptions = append(
+			routerOptions,
+			route.Constraint(constraint, i.Vehicles),
+		)
+	}
+	if f.threads > 0 {
+		routerOptions = append(routerOptions, route.Threads(f.threads))
+	}
+	// Stops may only stay unassigned if they have a penalty.
+	if len(i.UnassignedPenalties) > 0 {
+		routerOptions = append(
+			routerOptions,
+			route.Unassigned(i.UnassignedPenalties),
+		)
+	}
+	router, err := route.NewRouter(i.Stops, i.Vehicles, routerOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.format {
+		router.Format(outputFormat(p))
+	}
+
+	return router.Solver(opts)
+}
+
+// vehicleData implements the route.VehicleUpdater interface. The value of a
+// vehicle is the weighted sum of the objective terms on its route.
+type vehicleData struct {
+	objective objective
+	routeData routeData
+}
+
+func (v vehicleData) Update(
+	s route.PartialVehicle,
+) (route.VehicleUpdater, int, bool) {
+	times := s.Times()
+	costs := v.routeData.costs(
+		s.Route(),
+		times.EstimatedArrival,
+		times.EstimatedDeparture,
+	)
+	return v, v.objective.value(costs), true
+}
+
+// planData implements the PlanUpdater interface.
+type planData struct {
+	stops         []route.Stop
+	vehicleValues map[string]int
+	planValue     int
+	loading       loadingRules
+	unassigned    unassignedData
+	// penalties holds the unassigned penalty of every stop, if given.
+	penalties   []int
+	vehicleMap  map[string]int
+	stopIndices map[string]int
+	// objective is nil unless the value function is customized.
+	objective objective
+	routeData routeData
+	speeds    []vehicleSpeed
+}
+
+// newPlanData returns the data the value function and the output format need
+// about the input.
+func newPlanData(i Input, f features) (planData, error) {
+	// The objective is composed of the weighted terms given in the input.
+	var objective objective
+	if f.value {
+		var err error
+		if objective, err = newObjective(i.Objective); err != nil {
+			return planData{}, err
+		}
+	}
+	targets, err := targetWindows(i)
+	if err != nil {
+		return planData{}, err
+	}
+
+	stopIndices := make(map[string]int, len(i.Stops))
+	for idx, s := range i.Stops {
+		stopIndices[s.ID] = idx
+	}
+	loading, err := newLoadingRules(i)
+	if err != nil {
+		return planData{}, err
+	}
+	vehicleMap := make(map[string]int, len(i.Vehicles))
+	for idx, v := range i.Vehicles {
+		vehicleMap[v] = idx
+	}
+	speeds, err := newVehicleSpeeds(i)
+	if err != nil {
+		return planData{}, err
+	}
+
+	return planData{
+		stops:       i.Stops,
+		loading:     loading,
+		unassigned:  newUnassignedData(i, loading, speeds, f.loading),
+		penalties:   i.UnassignedPenalties,
+		vehicleMap:  vehicleMap,
+		stopIndices: stopIndices,
+		objective:   objective,
+		routeData:   newRouteData(i, targets),
+		speeds:      speeds,
+	}, nil
+}
+
+func (d planData) Update(
+	s route.PartialPlan,
+	vehicles []route.PartialVehicle,
+) (route.PlanUpdater, int, bool) {
+	// Perform a safe copy of the vehicle values map.
+	values := make(map[string]int, len(d.vehicleValues))
+	for vehicleID, i := range d.vehicleValues {
+		values[vehicleID] = i
+	}
+	d.vehicleValues = values
+
+	// Update the values for the vehicles that changed.
+	for _, vehicle := range vehicles {
+		vehicleID := vehicle.ID()
+		value := vehicle.Value()
+		d.planValue -= d.vehicleValues[vehicleID]
+		d.vehicleValues[vehicleID] = value
+		d.planValue += d.vehicleValues[vehicleID]
+	}
+
+	// Unassigned stops add their penalty to the value of the plan.
+	value := d.planValue
+	if len(d.penalties) > 0 {
+		for _, stop := range s.Unassigned().Slice() {
+			value += d.penalties[stop]
+		}
+	}
+
+	return d, value, true
+}
+
+// CustomConstraint is a custom type that implements Violated to fulfill the
+// VehicleConstraint interface. It ensures that items of labeled pickups are
+// dropped off in the order their loading mode allows.
+type CustomConstraint struct {
+	loading loadingRules
+}
+
+// Violated the method that must be implemented to be a used as a
+// VehicleConstraint.
+func (c CustomConstraint) Violated(
+	vehicle route.PartialVehicle,
+) (route.VehicleConstraint, bool) {
+	route := vehicle.Route()
+
+	// If only one stop is assigned, the constraint is feasible.
+	if len(route) <= 3 {
+		return c, false
+	}
+
+	// Omit the start and end locations of the vehicle.
+	return c, c.loading.violations(route[1:len(route)-1]) > 0
+}
@@ -0,0 +1,197 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// defaultVelocity is the velocity in m/s the router assumes for vehicles
+// without one.
+const defaultVelocity = 10.0
+
+// SpeedProfile scales the velocity of a vehicle by time of day, for example
+// to slow it down during rush hours. Every factor applies from its start until
+// the start of the next one. Before the first start, the last factor of the
+// previous day applies. Times of day are taken in the time zone of the shift
+// start of the vehicle, UTC if it has no shift.
+type SpeedProfile []SpeedFactor
+
+// SpeedFactor multiplies the velocity of a vehicle from the given time of day,
+// formatted as "15:04", on.
+type SpeedFactor struct {
+	Start  string  `json:"start"`
+	Factor float64 `json:"factor"`
+}
+
+// validate reports the first problem with the speed profile.
+func (p SpeedProfile) validate() error {
+	_, err := p.resolve(time.UTC)
+	return err
+}
+
+// resolve returns the speed profile in seconds of the day in the given time
+// zone.
+func (p SpeedProfile) resolve(location *time.Location) (speedProfile, error) {
+	s := speedProfile{
+		starts:   make([]int, len(p)),
+		factors:  make([]float64, len(p)),
+		location: location,
+	}
+	for k, f := range p {
+		start, err := time.Parse("15:04", f.Start)
+		if err != nil {
+			return speedProfile{}, fmt.Errorf("invalid start %q", f.Start)
+		}
+		if f.Factor <= 0 {
+			return speedProfile{}, fmt.Errorf(
+				"factor %v at %s is not positive", f.Factor, f.Start,
+			)
+		}
+		s.starts[k] = start.Hour()*3600 + start.Minute()*60
+		s.factors[k] = f.Factor
+		if k > 0 && s.starts[k] <= s.starts[k-1] {
+			return speedProfile{}, errors.New("starts are not in increasing order")
+		}
+	}
+	return s, nil
+}
+
+// speedProfile is a SpeedProfile resolved to seconds of the day. A profile
+// without factors keeps the velocity constant.
+type speedProfile struct {
+	starts   []int
+	factors  []float64
+	location *time.Location
+}
+
+// factor returns the speed factor at the given unix time, together with the
+// unix time it changes at next.
+func (p speedProfile) factor(t int) (float64, int) {
+	local := time.Unix(int64(t), 0).In(p.location)
+	midnight := time.Date(
+		local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, p.location,
+	)
+	second := t - int(midnight.Unix())
+
+	// The last factor of the previous day applies until the first start.
+	if second < p.starts[0] {
+		return p.factors[len(p.factors)-1], int(midnight.Unix()) + p.starts[0]
+	}
+	k := len(p.starts) - 1
+	for k > 0 && p.starts[k] > second {
+		k--
+	}
+	if k+1 < len(p.starts) {
+		return p.factors[k], int(midnight.Unix()) + p.starts[k+1]
+	}
+	// The next change is the first start of the following day.
+	tomorrow := midnight.AddDate(0, 0, 1)
+	return p.factors[k], int(tomorrow.Unix()) + p.starts[0]
+}
+
+// travelTime returns the seconds it takes to drive the given distance in
+// meters at the given velocity, departing at the given unix time. The speed
+// changes whenever the factor of the profile does along the way.
+func (p speedProfile) travelTime(
+	distance float64,
+	velocity float64,
+	departure int,
+) float64 {
+	if len(p.factors) == 0 {
+		return distance / velocity
+	}
+
+	t := float64(departure)
+	for {
+		factor, next := p.factor(int(math.Floor(t)))
+		speed := velocity * factor
+		if arrival := t + distance/speed; arrival <= float64(next) {
+			return arrival - float64(departure)
+		}
+		distance -= speed * (float64(next) - t)
+		t = float64(next)
+	}
+}
+
+// vehicleSpeed holds the velocity of a vehicle and its speed profile.
+type vehicleSpeed struct {
+	velocity float64
+	profile  speedProfile
+	// departure is used if the router does not know when the vehicle departs.
+	departure int
+}
+
+// newVehicleSpeeds returns the speed of every vehicle of the input. Vehicles
+// without a velocity drive with the default velocity of the router.
+func newVehicleSpeeds(i Input) ([]vehicleSpeed, error) {
+	speeds := make([]vehicleSpeed, len(i.Vehicles))
+	for v := range i.Vehicles {
+		speeds[v].velocity = defaultVelocity
+		if v < len(i.Velocities) && i.Velocities[v] > 0 {
+			speeds[v].velocity = i.Velocities[v]
+		}
+
+		location := time.UTC
+		if v < len(i.Shifts) {
+			location = i.Shifts[v].Start.Location()
+			speeds[v].departure = int(i.Shifts[v].Start.Unix())
+		}
+		if v < len(i.SpeedProfiles) && len(i.SpeedProfiles[v]) > 0 {
+			profile, err := i.SpeedProfiles[v].resolve(location)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"speed profile of vehicle %q: %v", i.Vehicles[v], err,
+				)
+			}
+			speeds[v].profile = profile
+		}
+	}
+	return speeds, nil
+}
+
+// travelTime returns the seconds it takes to drive between two positions,
+// departing at the given unix time. Legs from or to a location without a
+// position take no time.
+func (s vehicleSpeed) travelTime(from, to route.Position, departure int) float64 {
+	if from == (route.Position{}) || to == (route.Position{}) {
+		return 0
+	}
+	distance := route.HaversineByPoint().Cost(
+		route.Point{from.Lon, from.Lat},
+		route.Point{to.Lon, to.Lat},
+	)
+	return s.profile.travelTime(distance, s.velocity, departure)
+}
+
+// travelTimeMeasures returns the time-dependent travel time measure of every
+// vehicle, which the router computes the ETAs with. The positions are indexed
+// like the locations of the router.
+func travelTimeMeasures(
+	speeds []vehicleSpeed,
+	positions []route.Position,
+) []route.DependentByIndex {
+	measures := make([]route.DependentByIndex, len(speeds))
+	for v, speed := range speeds {
+		// Every measure needs its own copy of the speed of its vehicle.
+		speed := speed
+		measures[v] = route.DependentIndexed(
+			true,
+			func(from, to int, data *route.VehicleData) float64 {
+				if from >= len(positions) || to >= len(positions) {
+					return 0
+				}
+				departure := speed.departure
+				if data != nil && data.Index >= 0 &&
+					data.Index < len(data.Times.EstimatedDeparture) {
+					departure = data.Times.EstimatedDeparture[data.Index]
+				}
+				return speed.travelTime(positions[from], positions[to], departure)
+			},
+		)
+	}
+	return measures
+}
@@ -0,0 +1,137 @@
+package routing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+func TestSpeedProfileTravelTime(t *testing.T) {
+	// Half speed during the morning rush hour.
+	profile, err := SpeedProfile{
+		{Start: "07:00", Factor: 0.5},
+		{Start: "09:00", Factor: 1},
+	}.resolve(time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) int {
+		return int(day.Add(
+			time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute,
+		).Unix())
+	}
+
+	tests := []struct {
+		name      string
+		distance  float64
+		departure int
+		want      float64
+	}{
+		{
+			name:      "before the rush hour",
+			distance:  6000,
+			departure: at(5, 0),
+			want:      600,
+		},
+		{
+			name:      "during the rush hour",
+			distance:  6000,
+			departure: at(8, 0),
+			want:      1200,
+		},
+		{
+			name:      "into the rush hour",
+			distance:  6000,
+			departure: at(6, 55),
+			// 3000 m in the first 5 minutes, the rest at half speed.
+			want: 300 + 600,
+		},
+		{
+			name:      "out of the rush hour",
+			distance:  6000,
+			departure: at(8, 55),
+			// 1500 m in the last 5 minutes, the rest at full speed.
+			want: 300 + 450,
+		},
+		{
+			name:      "past midnight",
+			distance:  6000,
+			departure: at(23, 55),
+			want:      600,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := profile.travelTime(test.distance, 10, test.departure)
+			if math.Abs(got-test.want) > 1e-6 {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// A profile without factors keeps the velocity constant.
+	if got := (speedProfile{}).travelTime(6000, 10, at(8, 0)); got != 600 {
+		t.Errorf("constant velocity: got %v, want 600", got)
+	}
+}
+
+func TestSpeedProfileFactorWraps(t *testing.T) {
+	// Slow at night, from 22:00 until 06:00 the next day.
+	location := time.FixedZone("UTC-5", -5*3600)
+	profile, err := SpeedProfile{
+		{Start: "06:00", Factor: 1},
+		{Start: "22:00", Factor: 0.8},
+	}.resolve(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	night := time.Date(2023, 1, 1, 3, 0, 0, 0, location)
+	factor, next := profile.factor(int(night.Unix()))
+	morning := time.Date(2023, 1, 1, 6, 0, 0, 0, location)
+	if factor != 0.8 || next != int(morning.Unix()) {
+		t.Errorf("got %v until %v, want 0.8 until %v", factor, next, morning.Unix())
+	}
+
+	evening := time.Date(2023, 1, 1, 23, 0, 0, 0, location)
+	factor, next = profile.factor(int(evening.Unix()))
+	tomorrow := time.Date(2023, 1, 2, 6, 0, 0, 0, location)
+	if factor != 0.8 || next != int(tomorrow.Unix()) {
+		t.Errorf("got %v until %v, want 0.8 until %v", factor, next, tomorrow.Unix())
+	}
+}
+
+func TestSpeedProfileValidate(t *testing.T) {
+	for _, profile := range []SpeedProfile{
+		{{Start: "7:00am", Factor: 1}},
+		{{Start: "07:00", Factor: 0}},
+		{{Start: "09:00", Factor: 1}, {Start: "07:00", Factor: 0.5}},
+	} {
+		if err := profile.validate(); err == nil {
+			t.Errorf("expected an error for %v", profile)
+		}
+	}
+}
+
+func TestTravelTimeMeasuresPerVehicle(t *testing.T) {
+	positions := []route.Position{
+		{Lon: 7.0, Lat: 51.0},
+		{Lon: 7.1, Lat: 51.0},
+	}
+	speeds := []vehicleSpeed{{velocity: 1}, {velocity: 100}}
+	measures := travelTimeMeasures(speeds, positions)
+
+	distance := route.HaversineByPoint().Cost(
+		route.Point{positions[0].Lon, positions[0].Lat},
+		route.Point{positions[1].Lon, positions[1].Lat},
+	)
+	for v, speed := range speeds {
+		want := distance / speed.velocity
+		if got := measures[v].Cost(0, 1, nil); math.Abs(got-want) > 1e-6 {
+			t.Errorf("vehicle %d: got %v, want %v", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package routing
+
+import "github.com/nextmv-io/sdk/route"
+
+// formattedState is the output format of the model, as returned by
+// outputFormat.
+type formattedState struct {
+	Earliness         int                    `json:"earliness"`
+	Lateness          int                    `json:"lateness"`
+	TotalDuration     int                    `json:"total_duration"`
+	NumLifoViolations int                    `json:"num_lifo_violations"`
+	Objective         map[string]float64     `json:"objective"`
+	Unassigned        []route.Stop           `json:"unassigned"`
+	Vehicles          []route.PlannedVehicle `json:"vehicles"`
+}
+
+type custom struct {
+	Routing        routing `json:"routing"`
+	UsedVehicles   int     `json:"used_vehicles"`
+	Lateness       int     `json:"lateness"`
+	Earliness      int     `json:"earliness"`
+	TotalDuration  int     `json:"total_duration"`
+	LifoViolations int     `json:"lifo_violations"`
+	// Objective holds the weighted value of every objective term, if the
+	// model composes its objective of terms.
+	Objective map[string]float64 `json:"objective,omitempty"`
+}
+
+type routing struct {
+	Stops stops `json:"stops"`
+}
+
+type stops struct {
+	Unassigned int `json:"unassigned"`
+	Assigned   int `json:"assigned"`
+}
+
+// AssignedStops returns the number of stops assigned to a vehicle.
+func (c custom) AssignedStops() int {
+	return c.Routing.Stops.Assigned
+}
+
+// VehiclesUsed returns the number of vehicles with at least one stop.
+func (c custom) VehiclesUsed() int {
+	return c.UsedVehicles
+}
+
+// Statistics computes the custom statistics of a routing plan. Nil is
+// returned if the state holds no routing plan.
+func Statistics(s formattedState) any {
+	if s.Vehicles == nil {
+		return nil
+	}
+
+	assigned := 0
+	usedVehicles := 0
+	for _, v := range s.Vehicles {
+		if len(v.Route) > 2 {
+			assigned += len(v.Route) - 2
+			usedVehicles++
+		}
+	}
+
+	return custom{
+		Routing: routing{
+			Stops: stops{
+				Unassigned: len(s.Unassigned),
+				Assigned:   assigned,
+			},
+		},
+		UsedVehicles:   usedVehicles,
+		Lateness:       s.Lateness,
+		Earliness:      s.Earliness,
+		TotalDuration:  s.TotalDuration,
+		LifoViolations: s.NumLifoViolations,
+		Objective:      s.Objective,
+	}
+}
@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TargetWindow is the time window a stop should be arrived at in. Arriving
+// within the tolerance band around the window, given in seconds, is not
+// penalized. Every second of earliness or lateness beyond the band is
+// penalized according to the piecewise-linear penalties.
+type TargetWindow struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Tolerance int       `json:"tolerance,omitempty"`
+	Earliness []Slope   `json:"earliness,omitempty"`
+	Lateness  []Slope   `json:"lateness,omitempty"`
+}
+
+// Slope is the penalty per second of deviation that applies from the given
+// number of seconds outside the tolerance band on, until the next slope.
+type Slope struct {
+	From    int `json:"from"`
+	Penalty int `json:"penalty"`
+}
+
+// validate reports the first problem with the target window.
+func (w TargetWindow) validate() error {
+	if w.End.Before(w.Start) {
+		return errors.New("end is before start")
+	}
+	if w.Tolerance < 0 {
+		return errors.New("tolerance is negative")
+	}
+	for _, slopes := range [][]Slope{w.Earliness, w.Lateness} {
+		for i, s := range slopes {
+			if s.Penalty < 0 {
+				return errors.New("penalty is negative")
+			}
+			if s.From < 0 || i > 0 && s.From <= slopes[i-1].From {
+				return errors.New("slopes are not in increasing order from 0")
+			}
+		}
+	}
+	return nil
+}
+
+// deviation returns the seconds of earliness and lateness outside the
+// tolerance band for an arrival at the given unix time.
+func (w TargetWindow) deviation(eta int) (earliness, lateness int) {
+	if start := int(w.Start.Unix()) - w.Tolerance; eta < start {
+		earliness = start - eta
+	}
+	if end := int(w.End.Unix()) + w.Tolerance; eta > end {
+		lateness = eta - end
+	}
+	return earliness, lateness
+}
+
+// penalties returns the earliness and lateness penalty for an arrival at the
+// given unix time.
+func (w TargetWindow) penalties(eta int) (earliness, lateness int) {
+	early, late := w.deviation(eta)
+	return penalty(w.Earliness, early), penalty(w.Lateness, late)
+}
+
+// penalty returns the piecewise-linear penalty of a deviation.
+func penalty(slopes []Slope, deviation int) int {
+	penalty := 0
+	for i, s := range slopes {
+		if deviation <= s.From {
+			break
+		}
+		to := deviation
+		if i+1 < len(slopes) && slopes[i+1].From < to {
+			to = slopes[i+1].From
+		}
+		penalty += (to - s.From) * s.Penalty
+	}
+	return penalty
+}
+
+// targetWindows returns the target window of every stop, nil for stops
+// without one. Target windows are either given directly or derived from point
+// target times with linear earliness and lateness penalties.
+func targetWindows(i Input) ([]*TargetWindow, error) {
+	if len(i.TargetWindows) > 0 && len(i.TargetTimes) > 0 {
+		return nil, errors.New("give either target windows or target times")
+	}
+
+	if len(i.TargetWindows) > 0 {
+		if len(i.TargetWindows) != len(i.Stops) {
+			return nil, fmt.Errorf(
+				"%d target windows given for %d stops",
+				len(i.TargetWindows), len(i.Stops),
+			)
+		}
+		for s, w := range i.TargetWindows {
+			if w == nil {
+				continue
+			}
+			if err := w.validate(); err != nil {
+				return nil, fmt.Errorf(
+					"target window of stop %q: %v", i.Stops[s].ID, err,
+				)
+			}
+		}
+		return i.TargetWindows, nil
+	}
+
+	windows := make([]*TargetWindow, len(i.Stops))
+	for s, target := range i.TargetTimes {
+		if s >= len(windows) {
+			break
+		}
+		w := TargetWindow{Start: target, End: target}
+		if s < len(i.EarlinessPenalties) {
+			w.Earliness = []Slope{{Penalty: i.EarlinessPenalties[s]}}
+		}
+		if s < len(i.LatenessPenalties) {
+			w.Lateness = []Slope{{Penalty: i.LatenessPenalties[s]}}
+		}
+		windows[s] = &w
+	}
+	return windows, nil
+}
@@ -0,0 +1,248 @@
+package routing
+
+import (
+	"fmt"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+// Reasons why a stop is unassigned.
+const (
+	// capacityReason: the stop does not fit any vehicle.
+	capacityReason = "capacity"
+	// shiftReason: no vehicle can serve the stop on its own within its shift.
+	shiftReason = "shift"
+	// precedenceReason: the precedence partner of the stop is unassigned.
+	precedenceReason = "precedence"
+	// loadingReason: the stop fits into some route in time, but only by
+	// breaking the loading order of the items on board.
+	loadingReason = "loading"
+	// timeReason: no vehicle reaches the stop in time next to its other
+	// stops.
+	timeReason = "time"
+)
+
+// unassignedData holds the input data needed to explain why stops are
+// unassigned. All fields are indexed by stop or vehicle.
+type unassignedData struct {
+	stops      []route.Stop
+	quantities []int
+	capacities []int
+	starts     []route.Position
+	ends       []route.Position
+	shifts     []route.TimeWindow
+	speeds     []vehicleSpeed
+	services   []int
+	// partners holds the precedence partner of a stop and pickups whether a
+	// stop is the pickup of its precedence.
+	partners map[int]int
+	pickups  map[int]bool
+	loading  loadingRules
+	// loadingEnforced is set if the loading order is a constraint.
+	loadingEnforced bool
+}
+
+// newUnassignedData returns the data to explain unassigned stops of the input.
+func newUnassignedData(
+	i Input,
+	loading loadingRules,
+	speeds []vehicleSpeed,
+	loadingEnforced bool,
+) unassignedData {
+	stops := make(map[string]int, len(i.Stops))
+	for s, stop := range i.Stops {
+		stops[stop.ID] = s
+	}
+	services := make([]int, len(i.Stops))
+	for _, service := range i.ServiceTimes {
+		if s, ok := stops[service.ID]; ok {
+			services[s] = service.Duration
+		}
+	}
+	partners := make(map[int]int, 2*len(i.Precedences))
+	pickups := make(map[int]bool, len(i.Precedences))
+	for _, p := range i.Precedences {
+		pickup, ok1 := stops[p.PickUp]
+		dropoff, ok2 := stops[p.DropOff]
+		if ok1 && ok2 {
+			partners[pickup] = dropoff
+			partners[dropoff] = pickup
+			pickups[pickup] = true
+		}
+	}
+
+	return unassignedData{
+		stops:           i.Stops,
+		quantities:      i.Quantities,
+		capacities:      i.Capacities,
+		starts:          i.Starts,
+		ends:            i.Ends,
+		shifts:          i.Shifts,
+		speeds:          speeds,
+		services:        services,
+		partners:        partners,
+		pickups:         pickups,
+		loading:         loading,
+		loadingEnforced: loadingEnforced,
+	}
+}
+
+// explain returns the reason and an explanation for every unassigned stop,
+// given by stop index. The routes hold the stops planned for every vehicle,
+// by vehicle index. Reasons that rule out a stop on its own come first,
+// followed by its precedence partner and the loading order. If none of them
+// applies, the stop did not fit in time next to the other stops.
+func (d unassignedData) explain(
+	unassigned []int,
+	routes [][]int,
+) ([]string, []string) {
+	isUnassigned := make(map[int]bool, len(unassigned))
+	for _, s := range unassigned {
+		isUnassigned[s] = true
+	}
+
+	reasons := make([]string, len(unassigned))
+	explanations := make([]string, len(unassigned))
+	for u, s := range unassigned {
+		partner, hasPartner := d.partners[s]
+		if reason, explanation := d.infeasible(s); reason != "" {
+			reasons[u], explanations[u] = reason, explanation
+			continue
+		}
+		if hasPartner && isUnassigned[partner] {
+			if reason, _ := d.infeasible(partner); reason != "" {
+				reasons[u] = precedenceReason
+				explanations[u] = fmt.Sprintf(
+					"precedence partner %q is unassigned because of %s",
+					d.stops[partner].ID, reason,
+				)
+				continue
+			}
+		}
+		if d.loadingEnforced && d.loaded(s) && d.loadingConflict(s, routes) {
+			reasons[u] = loadingReason
+			explanations[u] = "fits in time only by breaking the loading order"
+			continue
+		}
+		reasons[u] = timeReason
+		explanations[u] = "no vehicle reaches it in time next to its other stops"
+	}
+	return reasons, explanations
+}
+
+// infeasible returns the reason why a stop cannot be assigned to any vehicle,
+// even on its own, together with an explanation. The reason is empty if some
+// vehicle can serve the stop.
+func (d unassignedData) infeasible(s int) (string, string) {
+	if s < len(d.quantities) && len(d.capacities) > 0 {
+		quantity := d.quantities[s]
+		if quantity < 0 {
+			quantity = -quantity
+		}
+		largest := d.capacities[0]
+		for _, capacity := range d.capacities {
+			if capacity > largest {
+				largest = capacity
+			}
+		}
+		if quantity > largest {
+			return capacityReason, fmt.Sprintf(
+				"quantity %d exceeds the largest capacity %d", quantity, largest,
+			)
+		}
+	}
+
+	if len(d.shifts) == 0 {
+		return "", ""
+	}
+	// The stop is visited together with its precedence partner.
+	visits := []int{s}
+	if partner, ok := d.partners[s]; ok {
+		if d.pickups[s] {
+			visits = []int{s, partner}
+		} else {
+			visits = []int{partner, s}
+		}
+	}
+	for v := range d.shifts {
+		if d.fitsShift(v, visits) {
+			return "", ""
+		}
+	}
+	return shiftReason, "no vehicle can serve it within its shift"
+}
+
+// loaded reports whether a stop picks up or drops off an item with a loading
+// mode.
+func (d unassignedData) loaded(s int) bool {
+	if _, ok := d.loading.modes[s]; ok {
+		return true
+	}
+	_, ok := d.loading.pickups[s]
+	return ok
+}
+
+// loadingConflict reports whether a stop, together with its precedence
+// partner, fits into some planned route within the shift of the vehicle, but
+// every such insertion breaks the loading order. The stops are tried at every
+// position of every route.
+func (d unassignedData) loadingConflict(s int, routes [][]int) bool {
+	pickup, dropoff := s, d.partners[s]
+	if !d.pickups[s] {
+		pickup, dropoff = dropoff, s
+	}
+
+	fits := false
+	for v, stops := range routes {
+		before := d.loading.violations(stops)
+		for p := 0; p <= len(stops); p++ {
+			for q := p; q <= len(stops); q++ {
+				inserted := make([]int, 0, len(stops)+2)
+				inserted = append(inserted, stops[:p]...)
+				inserted = append(inserted, pickup)
+				inserted = append(inserted, stops[p:q]...)
+				inserted = append(inserted, dropoff)
+				inserted = append(inserted, stops[q:]...)
+				if v < len(d.shifts) && !d.fitsShift(v, inserted) {
+					continue
+				}
+				if d.loading.violations(inserted) <= before {
+					return false
+				}
+				fits = true
+			}
+		}
+	}
+	return fits
+}
+
+// fitsShift reports whether vehicle v can drive from its start over the given
+// stops to its end within its shift.
+func (d unassignedData) fitsShift(v int, visits []int) bool {
+	var positions []route.Position
+	var services []int
+	if v < len(d.starts) {
+		positions = append(positions, d.starts[v])
+		services = append(services, 0)
+	}
+	for _, s := range visits {
+		positions = append(positions, d.stops[s].Position)
+		services = append(services, d.services[s])
+	}
+	if v < len(d.ends) {
+		positions = append(positions, d.ends[v])
+		services = append(services, 0)
+	}
+
+	// Travel times depend on the time of day, so the route is followed from
+	// the start of the shift on.
+	shift := d.shifts[v]
+	t := float64(shift.Start.Unix())
+	for p := range positions {
+		if p > 0 {
+			t += d.speeds[v].travelTime(positions[p-1], positions[p], int(t))
+		}
+		t += float64(services[p])
+	}
+	return t <= float64(shift.End.Unix())
+}
@@ -0,0 +1,142 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+func TestUnassignedExplain(t *testing.T) {
+	depot := route.Position{Lon: 7.0, Lat: 51.0}
+	near := route.Position{Lon: 7.01, Lat: 51.0}
+	// About 70 km away from the depot, which takes longer than the shift.
+	far := route.Position{Lon: 8.0, Lat: 51.0}
+	start := time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	i := Input{
+		Stops: []route.Stop{
+			{ID: "heavy", Position: near},
+			{ID: "far", Position: near},
+			{ID: "pickup", Position: near},
+			{ID: "dropoff", Position: far},
+			{ID: "stacked", Position: near},
+			{ID: "stacked-dropoff", Position: near},
+			{ID: "late", Position: near},
+		},
+		Vehicles:   []string{"v1"},
+		Starts:     []route.Position{depot},
+		Ends:       []route.Position{depot},
+		Quantities: []int{5, 0, 1, -1, 1, -1, 0},
+		Capacities: []int{4},
+		Velocities: []float64{10},
+		Shifts: []route.TimeWindow{
+			{Start: start, End: start.Add(time.Hour)},
+		},
+		Precedences: []route.Job{
+			{PickUp: "pickup", DropOff: "dropoff"},
+			{PickUp: "stacked", DropOff: "stacked-dropoff"},
+		},
+		Labels: []Label{{ID: "stacked", Label: "lifo"}},
+	}
+	i.Stops[1].Position = far
+
+	loading, err := newLoadingRules(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	speeds, err := newVehicleSpeeds(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newUnassignedData(i, loading, speeds, true)
+
+	// The labeled pickup fits into the empty route without breaking the
+	// loading order, so it is unassigned for lack of time.
+	routes := [][]int{nil}
+	reasons, _ := d.explain([]int{0, 1, 2, 4, 6}, routes)
+	want := []string{
+		capacityReason,
+		shiftReason,
+		shiftReason,
+		timeReason,
+		timeReason,
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("got %v, want %v", reasons, want)
+	}
+
+	// The pickup on its own is close by, but its dropoff is not.
+	i.Stops[3].Position = near
+	i.Quantities[3] = -5
+	d = newUnassignedData(i, loading, speeds, true)
+	reasons, _ = d.explain([]int{2, 3}, routes)
+	want = []string{precedenceReason, capacityReason}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("got %v, want %v", reasons, want)
+	}
+}
+
+func TestUnassignedExplainLoading(t *testing.T) {
+	at := func(lon float64) route.Position {
+		return route.Position{Lon: lon, Lat: 51.0}
+	}
+	start := time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	// The vehicle drives out and back along a line and has just enough time
+	// for it. The FIFO pair can only be inserted next to the stops at the
+	// same positions, where the LIFO items of the route are on board.
+	i := Input{
+		Stops: []route.Stop{
+			{ID: "outer", Position: at(7.1)},
+			{ID: "inner", Position: at(7.2)},
+			{ID: "inner-dropoff", Position: at(7.3)},
+			{ID: "outer-dropoff", Position: at(7.2)},
+			{ID: "queued", Position: at(7.2)},
+			{ID: "queued-dropoff", Position: at(7.3)},
+		},
+		Vehicles: []string{"v1"},
+		Starts:   []route.Position{at(7.0)},
+		Ends:     []route.Position{at(7.0)},
+		Shifts: []route.TimeWindow{
+			{Start: start, End: start.Add(80 * time.Minute)},
+		},
+		Precedences: []route.Job{
+			{PickUp: "outer", DropOff: "outer-dropoff"},
+			{PickUp: "inner", DropOff: "inner-dropoff"},
+			{PickUp: "queued", DropOff: "queued-dropoff"},
+		},
+		Labels: []Label{
+			{ID: "outer", Label: "lifo"},
+			{ID: "inner", Label: "lifo"},
+			{ID: "queued", Label: "fifo"},
+		},
+	}
+
+	loading, err := newLoadingRules(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	speeds, err := newVehicleSpeeds(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newUnassignedData(i, loading, speeds, true)
+
+	routes := [][]int{{0, 1, 2, 3}}
+	reasons, _ := d.explain([]int{4, 5}, routes)
+	want := []string{loadingReason, loadingReason}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("got %v, want %v", reasons, want)
+	}
+
+	// Without the loading constraint, the pair is unassigned for lack of
+	// time.
+	d = newUnassignedData(i, loading, speeds, false)
+	reasons, _ = d.explain([]int{4, 5}, routes)
+	want = []string{timeReason, timeReason}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("got %v, want %v", reasons, want)
+	}
+}
@@ -0,0 +1,207 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nextmv-io/sdk/store"
+)
+
+// ValidationError describes a single problem with the input. The field is the
+// name of the input field, the ID that of the stop or vehicle concerned.
+type ValidationError struct {
+	Field   string `json:"field"`
+	ID      string `json:"id,omitempty"`
+	Problem string `json:"problem"`
+}
+
+func (e ValidationError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Problem)
+	}
+	return fmt.Sprintf("%s %q: %s", e.Field, e.ID, e.Problem)
+}
+
+// ValidationErrors holds all problems found in the input.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// validationReport is the output of a solver created with ValidateOnly.
+type validationReport struct {
+	Valid    bool             `json:"valid"`
+	Problems ValidationErrors `json:"problems"`
+}
+
+// validationSolver returns a solver that reports the result of validating the
+// input as its only solution.
+func validationSolver(err error, opts store.Options) store.Solver {
+	report := validationReport{Valid: err == nil, Problems: ValidationErrors{}}
+	if errs, ok := err.(ValidationErrors); ok {
+		report.Problems = errs
+	} else if err != nil {
+		report.Problems = ValidationErrors{{Field: "input", Problem: err.Error()}}
+	}
+	s := store.New().Format(func(store.Store) any { return report })
+	return s.Satisfier(opts)
+}
+
+// validate checks the input before it is passed to the router and reports
+// every problem at once: per-stop and per-vehicle fields whose length does not
+// match the stops or vehicles, duplicate and unknown IDs and invalid
+// penalties, windows and loading modes.
+func validate(i Input) error {
+	var errs ValidationErrors
+	add := func(field, id, problem string, args ...any) {
+		errs = append(errs, ValidationError{
+			Field:   field,
+			ID:      id,
+			Problem: fmt.Sprintf(problem, args...),
+		})
+	}
+
+	stops := make(map[string]int, len(i.Stops))
+	for s, stop := range i.Stops {
+		if _, ok := stops[stop.ID]; ok {
+			add("stops", stop.ID, "duplicate ID")
+		}
+		stops[stop.ID] = s
+	}
+	vehicles := make(map[string]bool, len(i.Vehicles))
+	for _, vehicle := range i.Vehicles {
+		if vehicles[vehicle] {
+			add("vehicles", vehicle, "duplicate ID")
+		}
+		vehicles[vehicle] = true
+	}
+
+	// Optional fields that hold one value per stop or vehicle.
+	for _, f := range []struct {
+		field  string
+		length int
+	}{
+		{"quantities", len(i.Quantities)},
+		{"earliness_penalties", len(i.EarlinessPenalties)},
+		{"lateness_penalties", len(i.LatenessPenalties)},
+		{"target_times", len(i.TargetTimes)},
+		{"target_windows", len(i.TargetWindows)},
+		{"unassigned_penalties", len(i.UnassignedPenalties)},
+	} {
+		if f.length > 0 && f.length != len(i.Stops) {
+			add(f.field, "", "%d values given for %d stops", f.length, len(i.Stops))
+		}
+	}
+	for _, f := range []struct {
+		field  string
+		length int
+	}{
+		{"starts", len(i.Starts)},
+		{"ends", len(i.Ends)},
+		{"capacities", len(i.Capacities)},
+		{"velocities", len(i.Velocities)},
+		{"shifts", len(i.Shifts)},
+		{"speed_profiles", len(i.SpeedProfiles)},
+	} {
+		if f.length > 0 && f.length != len(i.Vehicles) {
+			add(
+				f.field, "", "%d values given for %d vehicles",
+				f.length, len(i.Vehicles),
+			)
+		}
+	}
+
+	for s, penalty := range i.EarlinessPenalties {
+		if penalty < 0 && s < len(i.Stops) {
+			add("earliness_penalties", i.Stops[s].ID, "negative penalty %d", penalty)
+		}
+	}
+	for s, penalty := range i.LatenessPenalties {
+		if penalty < 0 && s < len(i.Stops) {
+			add("lateness_penalties", i.Stops[s].ID, "negative penalty %d", penalty)
+		}
+	}
+	for s, penalty := range i.UnassignedPenalties {
+		if penalty < 0 && s < len(i.Stops) {
+			add("unassigned_penalties", i.Stops[s].ID, "negative penalty %d", penalty)
+		}
+	}
+	if len(i.TargetTimes) > 0 && len(i.TargetWindows) > 0 {
+		add("target_windows", "", "given together with target times")
+	}
+	for s, w := range i.TargetWindows {
+		if w == nil || s >= len(i.Stops) {
+			continue
+		}
+		if err := w.validate(); err != nil {
+			add("target_windows", i.Stops[s].ID, "%v", err)
+		}
+	}
+
+	for v, capacity := range i.Capacities {
+		if capacity < 0 && v < len(i.Vehicles) {
+			add("capacities", i.Vehicles[v], "negative capacity %d", capacity)
+		}
+	}
+	for v, velocity := range i.Velocities {
+		if velocity <= 0 && v < len(i.Vehicles) {
+			add("velocities", i.Vehicles[v], "velocity %v is not positive", velocity)
+		}
+	}
+	for v, shift := range i.Shifts {
+		if shift.End.Before(shift.Start) && v < len(i.Vehicles) {
+			add("shifts", i.Vehicles[v], "end is before start")
+		}
+	}
+
+	for v, profile := range i.SpeedProfiles {
+		if v >= len(i.Vehicles) {
+			continue
+		}
+		if err := profile.validate(); err != nil {
+			add("speed_profiles", i.Vehicles[v], "%v", err)
+		}
+	}
+
+	for _, service := range i.ServiceTimes {
+		if _, ok := stops[service.ID]; !ok {
+			add("service_times", service.ID, "unknown stop")
+		}
+		if service.Duration < 0 {
+			add("service_times", service.ID, "negative duration %d", service.Duration)
+		}
+	}
+
+	pickups := make(map[string]bool, len(i.Precedences))
+	for _, p := range i.Precedences {
+		if _, ok := stops[p.PickUp]; !ok {
+			add("precedences", p.PickUp, "unknown pickup")
+		}
+		if _, ok := stops[p.DropOff]; !ok {
+			add("precedences", p.DropOff, "unknown dropoff")
+		}
+		pickups[p.PickUp] = true
+	}
+
+	for _, l := range i.Labels {
+		if _, ok := stops[l.ID]; !ok {
+			add("labels", l.ID, "unknown stop")
+		} else if !pickups[l.ID] {
+			add("labels", l.ID, "stop is not a pickup")
+		}
+		if mode := strings.ToLower(l.Label); mode != lifoLoading &&
+			mode != fifoLoading {
+			add("labels", l.ID, "unknown loading mode %q", l.Label)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nextmv-io/sdk/route"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Input{
+		Stops:              []route.Stop{{ID: "a"}, {ID: "b"}},
+		Vehicles:           []string{"v1"},
+		Quantities:         []int{-1, 1},
+		Capacities:         []int{2},
+		Precedences:        []route.Job{{PickUp: "a", DropOff: "b"}},
+		EarlinessPenalties: []int{1, 1},
+		LatenessPenalties:  []int{2, 2},
+		TargetTimes:        []time.Time{{}, {}},
+		Labels:             []Label{{ID: "a", Label: "lifo"}},
+	}
+	if err := validate(valid); err != nil {
+		t.Fatalf("valid input: %v", err)
+	}
+
+	invalid := valid
+	invalid.Stops = []route.Stop{{ID: "a"}, {ID: "b"}, {ID: "a"}}
+	invalid.Capacities = []int{2, 3}
+	invalid.Precedences = []route.Job{{PickUp: "a", DropOff: "c"}}
+	invalid.LatenessPenalties = []int{2, -2, 2}
+	invalid.Labels = []Label{{ID: "b", Label: "stack"}}
+
+	err := validate(invalid)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("got %v, want validation errors", err)
+	}
+	want := ValidationErrors{
+		{Field: "stops", ID: "a", Problem: "duplicate ID"},
+		{Field: "quantities", Problem: "2 values given for 3 stops"},
+		{Field: "earliness_penalties", Problem: "2 values given for 3 stops"},
+		{Field: "target_times", Problem: "2 values given for 3 stops"},
+		{Field: "capacities", Problem: "2 values given for 1 vehicles"},
+		{Field: "lateness_penalties", ID: "b", Problem: "negative penalty -2"},
+		{Field: "precedences", ID: "c", Problem: "unknown dropoff"},
+		{Field: "labels", ID: "b", Problem: "stop is not a pickup"},
+		{Field: "labels", ID: "b", Problem: `unknown loading mode "stack"`},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got\n%v\nwant\n%v", errs, want)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"example.com/your_project/routing/internal/routing"
+	"example.com/your_project/routing/internal/routing/routingtest"
+	"github.com/nextmv-io/sdk/store"
+)
+
+func TestGolden(t *testing.T) {
+	// A single thread keeps the runs comparable, within the tolerances of the
+	// golden test.
+	solver := func(i routing.Input, opts store.Options) (store.Solver, error) {
+		opts = routing.SolverOptions(opts)
+		options := append(routing.ValueTemplate(), routing.Threads(1))
+		return routing.NewSolver(i, opts, options...)
+	}
+	routingtest.Golden(t, solver, "../data")
+}
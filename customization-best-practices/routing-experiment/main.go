@@ -0,0 +1,272 @@
+// package main runs the routing templates on a set of inputs with several
+// durations and seeds and compares their results.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/your_project/routing/internal/routing"
+	"github.com/nextmv-io/sdk/run"
+	"github.com/nextmv-io/sdk/run/decode"
+	"github.com/nextmv-io/sdk/run/encode"
+	"github.com/nextmv-io/sdk/store"
+)
+
+var (
+	variantsFlag = flag.String(
+		"variants",
+		"default,value,constraint",
+		"comma-separated variants to run, any of default, value and constraint",
+	)
+	inputsFlag = flag.String(
+		"inputs",
+		"../data/input*.json",
+		"glob of the input files",
+	)
+	durationsFlag = flag.String(
+		"durations",
+		"1s,5s",
+		"comma-separated time limits of every run",
+	)
+	seedsFlag = flag.String(
+		"seeds",
+		"1",
+		"comma-separated random seeds of every run",
+	)
+	csvFlag = flag.String(
+		"csv",
+		"",
+		"path of the CSV file with the results, printed after the table if empty",
+	)
+)
+
+// variants maps the name of a variant to the features of the routing package
+// its template picks.
+var variants = map[string][]routing.Option{
+	"default":    routing.DefaultTemplate(),
+	"value":      routing.ValueTemplate(),
+	"constraint": routing.ConstraintTemplate(),
+}
+
+// experiment is a single run of a variant on an input.
+type experiment struct {
+	variant  string
+	input    string
+	duration time.Duration
+	seed     int64
+}
+
+// result holds the statistics of the best solution of an experiment and the
+// time the run took.
+type result struct {
+	experiment
+	value          float64
+	lateness       int
+	earliness      int
+	lifoViolations int
+	runtime        time.Duration
+}
+
+func main() {
+	flag.Parse()
+
+	experiments, err := experiments()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inputs := make(map[string]routing.Input)
+	results := make([]result, 0, len(experiments))
+	for _, e := range experiments {
+		i, ok := inputs[e.input]
+		if !ok {
+			if i, err = readInput(e.input); err != nil {
+				log.Fatal(err)
+			}
+			inputs[e.input] = i
+		}
+
+		r, err := runExperiment(e, i)
+		if err != nil {
+			log.Fatalf("%s on %s: %v", e.variant, e.input, err)
+		}
+		results = append(results, r)
+	}
+
+	if err := writeTable(os.Stdout, results); err != nil {
+		log.Fatal(err)
+	}
+	if *csvFlag == "" {
+		fmt.Println()
+		if err := writeCSV(os.Stdout, results); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	file, err := os.Create(*csvFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = writeCSV(file, results)
+	// the first error is the most important
+	if tempErr := file.Close(); err == nil {
+		err = tempErr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// experiments returns every combination of the inputs, durations, seeds and
+// variants given by the flags. Runs on the same input are grouped, so that
+// the variants can be compared next to each other.
+func experiments() ([]experiment, error) {
+	names := split(*variantsFlag)
+	for _, name := range names {
+		if _, ok := variants[name]; !ok {
+			return nil, fmt.Errorf("unknown variant %q", name)
+		}
+	}
+
+	paths, err := filepath.Glob(*inputsFlag)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no inputs match %q", *inputsFlag)
+	}
+	sort.Strings(paths)
+
+	var durations []time.Duration
+	for _, d := range split(*durationsFlag) {
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, err
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration %v is not positive", duration)
+		}
+		durations = append(durations, duration)
+	}
+
+	var seeds []int64
+	for _, s := range split(*seedsFlag) {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, seed)
+	}
+
+	var experiments []experiment
+	for _, path := range paths {
+		for _, duration := range durations {
+			for _, seed := range seeds {
+				for _, name := range names {
+					experiments = append(experiments, experiment{
+						variant:  name,
+						input:    path,
+						duration: duration,
+						seed:     seed,
+					})
+				}
+			}
+		}
+	}
+	return experiments, nil
+}
+
+// split returns the non-empty values of a comma-separated list.
+func split(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// readInput decodes the input at path, which may be compressed.
+func readInput(path string) (routing.Input, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return routing.Input{}, err
+	}
+	// The decoder closes the file.
+	return routing.GenericDecoder[routing.Input](decode.JSON())(
+		context.Background(),
+		file,
+	)
+}
+
+// runExperiment solves the input with the variant of the experiment and
+// returns the statistics that GenericEncoder reports for the best solution.
+func runExperiment(e experiment, i routing.Input) (result, error) {
+	opts := routing.SolverOptions(store.DefaultOptions())
+	opts.Limits.Duration = e.duration
+	opts.Random.Seed = e.seed
+
+	start := time.Now()
+	solver, err := routing.NewSolver(i, opts, variants[e.variant]...)
+	if err != nil {
+		return result{}, err
+	}
+
+	var cfg run.CLIRunnerConfig
+	cfg.Runner.Output.Solutions = "last"
+	encoder := routing.GenericEncoder[store.Solution, store.Options](
+		encode.JSON(),
+		routing.StoreStatistics[store.Solution](routing.Statistics),
+	)
+	var b bytes.Buffer
+	err = encoder.Encode(
+		context.Background(),
+		solver.All(context.Background()),
+		&b,
+		cfg,
+		opts,
+	)
+	if err != nil {
+		return result{}, err
+	}
+	runtime := time.Since(start)
+
+	output := struct {
+		Statistics struct {
+			Result struct {
+				Value  float64 `json:"value"`
+				Custom struct {
+					Lateness       int `json:"lateness"`
+					Earliness      int `json:"earliness"`
+					LifoViolations int `json:"lifo_violations"`
+				} `json:"custom"`
+			} `json:"result"`
+		} `json:"statistics"`
+	}{}
+	if err := json.Unmarshal(b.Bytes(), &output); err != nil {
+		return result{}, err
+	}
+
+	statistics := output.Statistics.Result
+	return result{
+		experiment:     e,
+		value:          statistics.Value,
+		lateness:       statistics.Custom.Lateness,
+		earliness:      statistics.Custom.Earliness,
+		lifoViolations: statistics.Custom.LifoViolations,
+		runtime:        runtime,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+)
+
+// header of the table and the CSV.
+var header = []string{
+	"input",
+	"duration",
+	"seed",
+	"variant",
+	"value",
+	"lateness",
+	"earliness",
+	"lifo_violations",
+	"runtime",
+}
+
+// fields returns the values of a result in the order of the header. Runtimes
+// are given in seconds.
+func (r result) fields() []string {
+	return []string{
+		r.input,
+		r.duration.String(),
+		strconv.FormatInt(r.seed, 10),
+		r.variant,
+		strconv.FormatFloat(r.value, 'f', -1, 64),
+		strconv.Itoa(r.lateness),
+		strconv.Itoa(r.earliness),
+		strconv.Itoa(r.lifoViolations),
+		strconv.FormatFloat(r.runtime.Seconds(), 'f', 3, 64),
+	}
+}
+
+// writeTable writes the results as an aligned table. Inputs are shortened to
+// their file name.
+func writeTable(w io.Writer, results []result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, h := range header {
+		fmt.Fprintf(tw, "%s\t", h)
+	}
+	fmt.Fprintln(tw)
+	for _, r := range results {
+		fields := r.fields()
+		fields[0] = filepath.Base(r.input)
+		for _, f := range fields {
+			fmt.Fprintf(tw, "%s\t", f)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// writeCSV writes the results as CSV with a header.
+func writeCSV(w io.Writer, results []result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := cw.Write(r.fields()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}